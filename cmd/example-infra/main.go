@@ -16,8 +16,9 @@ func main() {
 		createInfrastructure(hq)
 	})
 
-	// this will start the parsing the os.Args given to the application, and execute the matching CLI command
-	hq.Run()
+	// this will start the parsing the os.Args given to the application, and execute the matching CLI command. A failed
+	// run ends the program with the exit code matching the error
+	hq.RunAndExit()
 }
 
 func createInfrastructure(hq hq.HQ) {
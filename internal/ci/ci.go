@@ -0,0 +1,174 @@
+// Package ci integrates the console output with the CI systems Azure DevOps and GitHub Actions, via their logging
+// commands: output can be grouped into collapsible sections, and errors and warnings are shown as native annotations.
+// Outside a supported CI system (or before the integration is activated), all functions are no-ops.
+package ci
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+)
+
+// System is a CI system with native support for collapsible sections and annotations
+type System string
+
+const (
+	None          System = ""
+	AzureDevOps   System = "azure-devops"
+	GitHubActions System = "github-actions"
+)
+
+// maxAnnotatedErrors limits the remembered errors, which are not annotated again
+const maxAnnotatedErrors = 100
+
+var (
+	mutex     sync.Mutex
+	active    System
+	output    io.Writer = os.Stdout
+	groupOpen bool
+	annotated []error
+)
+
+// Detect returns the CI system the program is running in, based on the environment variables set by the CI systems
+func Detect() System {
+	if strings.EqualFold(os.Getenv("TF_BUILD"), "true") {
+		return AzureDevOps
+	}
+
+	if strings.EqualFold(os.Getenv("GITHUB_ACTIONS"), "true") {
+		return GitHubActions
+	}
+
+	return None
+}
+
+// Activate enables the integration for the given CI system, None disables it
+func Activate(system System) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	active = system
+	groupOpen = false
+	annotated = nil
+}
+
+// Active returns the CI system of the activated integration
+func Active() System {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return active
+}
+
+// StartGroup starts a collapsible section with the given title, and returns the function to end it. Since the CI
+// systems do not support nested sections, a group started while another group is open is ignored (the returned
+// function does nothing).
+func StartGroup(title string) (end func()) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if active == None || groupOpen {
+		return func() {}
+	}
+
+	groupOpen = true
+	title = secrets.Redact(title)
+
+	switch active {
+	case AzureDevOps:
+		fmt.Fprintln(output, "##[group]"+escapeAzureDevOps(title))
+	case GitHubActions:
+		fmt.Fprintln(output, "::group::"+escapeGitHubActions(title))
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(endGroup)
+	}
+}
+
+func endGroup() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !groupOpen {
+		// integration was re-activated in the meantime
+		return
+	}
+
+	groupOpen = false
+
+	switch active {
+	case AzureDevOps:
+		fmt.Fprintln(output, "##[endgroup]")
+	case GitHubActions:
+		fmt.Fprintln(output, "::endgroup::")
+	}
+}
+
+// AnnotateError shows the error as error annotation of the CI run. An error is annotated only once, even if it is
+// returned (and wrapped) multiple times on its way up the call stack.
+func AnnotateError(err error) {
+	if err == nil {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if active == None {
+		return
+	}
+
+	for _, previous := range annotated {
+		if errors.Is(err, previous) {
+			return
+		}
+	}
+
+	annotated = append(annotated, err)
+	if len(annotated) > maxAnnotatedErrors {
+		annotated = annotated[1:]
+	}
+
+	annotate("error", err.Error())
+}
+
+// AnnotateWarning shows the message as warning annotation of the CI run
+func AnnotateWarning(message string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if active == None {
+		return
+	}
+
+	annotate("warning", message)
+}
+
+func annotate(level string, message string) {
+	message = secrets.Redact(message)
+
+	switch active {
+	case AzureDevOps:
+		fmt.Fprintf(output, "##vso[task.logissue type=%s]%s\n", level, escapeAzureDevOps(message))
+	case GitHubActions:
+		fmt.Fprintf(output, "::%s::%s\n", level, escapeGitHubActions(message))
+	}
+}
+
+// escapeAzureDevOps escapes the message of a logging command, so that it stays on one line
+func escapeAzureDevOps(message string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(message)
+}
+
+// escapeGitHubActions escapes the message of a workflow command, so that it stays on one line
+func escapeGitHubActions(message string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(message)
+}
@@ -0,0 +1,79 @@
+package ci
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func activateWithOutput(t *testing.T, system System) *strings.Builder {
+	var buffer strings.Builder
+	output = &buffer
+	Activate(system)
+
+	t.Cleanup(func() {
+		Activate(None)
+		output = os.Stdout
+	})
+
+	return &buffer
+}
+
+func Test_GroupsAreNotNested(t *testing.T) {
+	buffer := activateWithOutput(t, GitHubActions)
+
+	// Act
+	endPhase := StartGroup("Terraform init")
+	endCommand := StartGroup("terraform init -upgrade")
+	endCommand()
+	endPhase()
+	endPhase()
+	StartGroup("Terraform plan")()
+
+	// Assert
+	assert.Equal(t, "::group::Terraform init\n::endgroup::\n::group::Terraform plan\n::endgroup::\n", buffer.String())
+}
+
+func Test_ErrorsAreAnnotatedOnceAndEscaped(t *testing.T) {
+	buffer := activateWithOutput(t, AzureDevOps)
+	secrets.Register("annotated-secret-value")
+	err := errors.New("command failed with 100%\nsecret annotated-secret-value")
+
+	// Act
+	AnnotateError(err)
+	AnnotateError(fmt.Errorf("deployment failed: %w", err))
+	AnnotateWarning("sops is not installed")
+
+	// Assert
+	assert.Equal(t, "##vso[task.logissue type=error]command failed with 100%AZP25%0Asecret ***\n"+
+		"##vso[task.logissue type=warning]sops is not installed\n", buffer.String())
+}
+
+func Test_NothingIsWrittenOutsideCI(t *testing.T) {
+	buffer := activateWithOutput(t, None)
+
+	// Act
+	StartGroup("Terraform init")()
+	AnnotateError(errors.New("failure"))
+	AnnotateWarning("warning")
+
+	// Assert
+	assert.Empty(t, buffer.String())
+}
+
+func Test_CISystemIsDetectedFromEnvironmentVariables(t *testing.T) {
+	t.Setenv("TF_BUILD", "")
+	t.Setenv("GITHUB_ACTIONS", "")
+	assert.Equal(t, None, Detect())
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.Equal(t, GitHubActions, Detect())
+
+	t.Setenv("TF_BUILD", "True")
+	assert.Equal(t, AzureDevOps, Detect())
+}
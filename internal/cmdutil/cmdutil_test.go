@@ -7,19 +7,19 @@ import (
 )
 
 // MockFunction simulates a function that can fail up to a certain number of times before succeeding.
-func MockFunction(maxFailures string) func(string) (string, error) {
+func MockFunction(maxFailures string) func() error {
 	attempts := uint64(0)
 	maxFailuresInternal, _ := strconv.ParseUint(maxFailures, 10, 64)
-	return func(cmd string) (string, error) {
+	return func() error {
 		if attempts < maxFailuresInternal {
 			attempts++
-			return "", errors.New("error")
+			return errors.New("error")
 		}
-		return "success", nil
+		return nil
 	}
 }
 
-func TestExecuteWithRetryCommand(t *testing.T) {
+func TestExecuteFunctionWithRetry(t *testing.T) {
 	tests := []struct {
 		name        string
 		maxFailures string
@@ -34,7 +34,7 @@ func TestExecuteWithRetryCommand(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			function := MockFunction(tt.maxFailures)
-			err := ExecuteWithRetry(function, "cmd", tt.maxAttempts)
+			err := ExecuteFunctionWithRetry(function, tt.maxAttempts)
 			if tt.expectError && err == nil {
 				t.Errorf("Expected an error but got none")
 			} else if !tt.expectError && err != nil {
@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LogFile is the shared writer of a single log file. All writers of the same file (the log entries written by the
+// logrus hook, and the command output written via OutputAppender) go through the same instance, which keeps the file
+// open, serializes the writes and rotates the file once it gets too large. Each write is written as a whole, so writes
+// consisting of complete lines (or complete log entries) never interleave with other writes.
+// Writes are buffered, and written to the file once the buffer is full, at the latest after logFileFlushInterval, and
+// by Flush, Reopen and FlushLogFiles (called at the end of the program).
+type LogFile struct {
+	mutex     sync.Mutex
+	file      *lumberjack.Logger
+	buffer    *bufio.Writer
+	formatter OutputFormatter
+
+	// flushTimer flushes the buffer periodically, nil while the buffer is empty
+	flushTimer *time.Timer
+}
+
+const (
+	// logFileBufferSize is the size of the write buffer of a LogFile
+	logFileBufferSize = 64 * 1024
+
+	// logFileFlushInterval is the maximum time the writes stay in the buffer
+	logFileFlushInterval = time.Second
+)
+
+var (
+	logFilesMutex sync.Mutex
+	logFiles      = make(map[string]*LogFile)
+)
+
+// SharedLogFile returns the shared writer of the given log file, creating it on first use. Relative file names are
+// resolved from the current working directory.
+func SharedLogFile(logFileName string) *LogFile {
+	path, err := filepath.Abs(logFileName)
+	if err != nil {
+		path = logFileName
+	}
+
+	logFilesMutex.Lock()
+	defer logFilesMutex.Unlock()
+
+	if logFile, exists := logFiles[path]; exists {
+		return logFile
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    50, // megabytes
+		MaxBackups: 3,
+		MaxAge:     90, // days
+	}
+
+	logFile := &LogFile{file: file, buffer: bufio.NewWriterSize(file, logFileBufferSize)}
+
+	logFiles[path] = logFile
+	return logFile
+}
+
+// FlushLogFiles flushes the buffered writes of all shared log files. Called by HQ at the end of the run.
+func FlushLogFiles() error {
+	logFilesMutex.Lock()
+	defer logFilesMutex.Unlock()
+
+	var errs []error
+	for _, logFile := range logFiles {
+		errs = append(errs, logFile.Flush())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *LogFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	n, err := f.buffer.Write(p)
+
+	if f.buffer.Buffered() > 0 && f.flushTimer == nil {
+		// errors are returned by the next write (the buffer keeps failing once a write to the file failed)
+		f.flushTimer = time.AfterFunc(logFileFlushInterval, func() { f.Flush() })
+	}
+
+	return n, err
+}
+
+// Flush writes the buffered writes to the file
+func (f *LogFile) Flush() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.flush()
+}
+
+func (f *LogFile) flush() error {
+	if f.flushTimer != nil {
+		f.flushTimer.Stop()
+		f.flushTimer = nil
+	}
+
+	return f.buffer.Flush()
+}
+
+// WriteLine writes a single line (without the line break) which is not written by a command itself, like the command
+// line of a started command. The line is formatted with the output formatter of the file, if configured.
+func (f *LogFile) WriteLine(line string, command string) error {
+	formatted := []byte(line + "\n")
+	if formatter := f.outputFormatter(); formatter != nil {
+		formatted = formatter(line, command, "")
+	}
+
+	_, err := f.Write(formatted)
+	return err
+}
+
+// SetOutputFormatter sets the formatter for the command output written to the file. Nil (default) writes the output
+// as it is.
+func (f *LogFile) SetOutputFormatter(formatter OutputFormatter) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.formatter = formatter
+}
+
+func (f *LogFile) outputFormatter() OutputFormatter {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.formatter
+}
+
+// Reopen flushes the buffered writes and closes the file, so that it is opened again on the next write. Needed if the
+// file was moved or deleted in the meantime, otherwise the writes would go to the old file.
+func (f *LogFile) Reopen() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	err := errors.Join(f.flush(), f.file.Close())
+
+	// a failed write leaves the buffer in an error state, which is reset with the reopened file
+	f.buffer.Reset(f.file)
+
+	return err
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LogFileIsWrittenOnFlush(t *testing.T) {
+	logFileName := filepath.Join(t.TempDir(), "test.log")
+	logFile := SharedLogFile(logFileName)
+	defer logFile.Reopen()
+
+	// Act
+	logFile.Write([]byte("first line\n"))
+	logFile.Write([]byte("second line\n"))
+	beforeFlush, _ := os.ReadFile(logFileName)
+	err := FlushLogFiles()
+	afterFlush, _ := os.ReadFile(logFileName)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, string(beforeFlush))
+	assert.Equal(t, "first line\nsecond line\n", string(afterFlush))
+}
+
+func Test_LogFileIsFlushedPeriodically(t *testing.T) {
+	logFileName := filepath.Join(t.TempDir(), "test.log")
+	logFile := SharedLogFile(logFileName)
+	defer logFile.Reopen()
+
+	// Act
+	logFile.Write([]byte("first line\n"))
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		content, _ := os.ReadFile(logFileName)
+		return string(content) == "first line\n"
+	}, 5*logFileFlushInterval, 50*time.Millisecond)
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPendingBytes limits the output held back while waiting for the end of a line, so that output without any line
+// breaks (like progress bars redrawn with carriage returns) is still written
+const maxPendingBytes = 64 * 1024
+
+// OutputFormatter formats a single line of command output (without the line break) as log entry, including the line
+// break. The command is the executed command line (secrets masked), the stream either "stdout" or "stderr", or empty
+// for lines not written by the command itself.
+type OutputFormatter func(line string, command string, stream string) []byte
+
+var (
+	consoleFormatterMutex sync.Mutex
+	consoleFormatter      OutputFormatter
+)
+
+// SetConsoleOutputFormatter sets the formatter for the command output shown on the console. Nil (default) writes the
+// output as it is.
+func SetConsoleOutputFormatter(formatter OutputFormatter) {
+	consoleFormatterMutex.Lock()
+	defer consoleFormatterMutex.Unlock()
+
+	consoleFormatter = formatter
+}
+
+// OutputAppender simple io.writer adapter, which will append the command output to the configured log file or
+// console. The output is buffered until a line is complete, and only complete lines are written, so that the output of
+// concurrent appenders never interleaves mid-line. If a formatter is configured (see SetConsoleOutputFormatter and
+// LogFile.SetOutputFormatter), every line is written as a separate log entry. Call Flush after the last write, to write
+// out an incomplete last line.
+type OutputAppender struct {
+	mutex   sync.Mutex
+	target  io.Writer
+	format  OutputFormatter
+	command string
+	stream  string
+	pending []byte
+
+	// passThrough writes the output immediately, without waiting for complete lines
+	passThrough bool
+}
+
+// NewLogFileAppender creates an OutputAppender for the given log file, for the output of the given command and stream
+// (see OutputFormatter)
+func NewLogFileAppender(logFileName string, command string, stream string) *OutputAppender {
+	logFile := SharedLogFile(logFileName)
+	return &OutputAppender{target: logFile, format: logFile.outputFormatter(), command: command, stream: stream}
+}
+
+// NewConsoleAppender creates an OutputAppender for the given console writer (e.g. os.Stdout), for the output of the
+// given command and stream (see OutputFormatter). Without a console formatter, the output is written immediately, so
+// that incomplete lines (like prompts) are shown on the console.
+func NewConsoleAppender(writer io.Writer, command string, stream string) *OutputAppender {
+	consoleFormatterMutex.Lock()
+	defer consoleFormatterMutex.Unlock()
+
+	return &OutputAppender{target: writer, format: consoleFormatter, command: command, stream: stream,
+		passThrough: consoleFormatter == nil}
+}
+
+func (w *OutputAppender) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.passThrough {
+		return w.target.Write(p)
+	}
+
+	w.pending = append(w.pending, p...)
+
+	end := len(w.pending)
+	if end < maxPendingBytes {
+		end = bytes.LastIndexByte(w.pending, '\n') + 1
+	}
+
+	if end == 0 {
+		return len(p), nil
+	}
+
+	err := w.write(w.pending[:end])
+	w.pending = append(w.pending[:0], w.pending[end:]...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes out an incomplete last line, followed by a line break
+func (w *OutputAppender) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	err := w.write(append(w.pending, '\n'))
+	w.pending = nil
+
+	return err
+}
+
+// write writes the given lines at once, formatted as separate entries if a formatter is configured
+func (w *OutputAppender) write(lines []byte) error {
+	if w.format == nil {
+		_, err := w.target.Write(lines)
+		return err
+	}
+
+	var entries []byte
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			entries = append(entries, w.format(string(line), w.command, w.stream)...)
+		}
+	}
+
+	_, err := w.target.Write(entries)
+	return err
+}
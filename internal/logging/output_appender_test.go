@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConcurrentAppendersWriteOnlyCompleteLines(t *testing.T) {
+	logFileName := filepath.Join(t.TempDir(), "test.log")
+	defer SharedLogFile(logFileName).Reopen()
+
+	var writers sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+
+		go func() {
+			defer writers.Done()
+
+			appender := NewLogFileAppender(logFileName, "", "stdout")
+			for line := 0; line < 200; line++ {
+				// every line is split over multiple writes
+				fmt.Fprintf(appender, "writer %d ", i)
+				fmt.Fprintf(appender, "line %d\nwriter %d ", line, i)
+				fmt.Fprint(appender, "continued\n")
+			}
+
+			fmt.Fprintf(appender, "writer %d incomplete", i)
+			appender.Flush()
+		}()
+	}
+
+	writers.Wait()
+	assert.NoError(t, SharedLogFile(logFileName).Flush())
+
+	// Assert
+	content, err := os.ReadFile(logFileName)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(t, lines, 4*401)
+
+	validLine := regexp.MustCompile(`^writer \d (line \d+|continued|incomplete)$`)
+	for _, line := range lines {
+		assert.Regexp(t, validLine, line)
+	}
+}
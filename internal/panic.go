@@ -1,12 +1,23 @@
 package internal
 
 import (
+	"github.com/conplementag/cops-hq/v2/internal/ci"
 	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/sirupsen/logrus"
 )
 
+// ReturnErrorOrPanic returns the error, or panics with it in panic mode (see error_handling.PanicOnAnyError)
 func ReturnErrorOrPanic(err error) error {
-	if err != nil && error_handling.PanicOnAnyError {
+	return ReturnErrorOrPanicWith(error_handling.Default, err)
+}
+
+// ReturnErrorOrPanicWith returns the error, or panics with it if the given policy says so. Used by everything bound to
+// an executor or HQ instance, which carry their own policy.
+func ReturnErrorOrPanicWith(policy error_handling.Policy, err error) error {
+	// shown as annotation of the CI run, if running in a CI system (only once, even if returned by multiple layers)
+	ci.AnnotateError(err)
+
+	if err != nil && policy.PanicsOnError() {
 		// we log the error, so it ends up in the log file as well. Consequence: it will be shown twice in the stdout, but
 		// this we have to live with
 		logrus.Error(err)
@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"io"
+	"sync"
+)
+
+// RedactingWriter is an io.Writer adapter, which masks all registered secrets before writing to the underlying writer.
+// Since a secret could be split over multiple writes, the end of a write which could be the beginning of a secret is
+// held back until the next write. Call Flush after the last write, to write out anything held back.
+type RedactingWriter struct {
+	mutex   sync.Mutex
+	writer  io.Writer
+	pending string
+}
+
+func NewRedactingWriter(writer io.Writer) *RedactingWriter {
+	return &RedactingWriter{writer: writer}
+}
+
+func (w *RedactingWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.pending == "" && !hasSecrets() {
+		return w.writer.Write(p)
+	}
+
+	text := Redact(w.pending + string(p))
+	heldBack := partialSecretSuffixLength(text)
+	w.pending = text[len(text)-heldBack:]
+
+	if _, err := io.WriteString(w.writer, text[:len(text)-heldBack]); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes out anything held back by previous writes
+func (w *RedactingWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.pending == "" {
+		return nil
+	}
+
+	_, err := io.WriteString(w.writer, Redact(w.pending))
+	w.pending = ""
+
+	return err
+}
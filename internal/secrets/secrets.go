@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask is the replacement for every registered secret value
+const Mask = "***"
+
+// minimumSecretLength protects from masking very short values, which would make the output unreadable (e.g.
+// registering "1" as secret would mask every single 1 in the output)
+const minimumSecretLength = 4
+
+var registry = &secretRegistry{}
+
+type secretRegistry struct {
+	mutex    sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}
+
+// Register adds the value to the process wide list of secrets, which will be masked by Redact. Empty values, and values
+// shorter than 4 characters, are ignored.
+func Register(value string) {
+	if len(value) < minimumSecretLength {
+		return
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	for _, existing := range registry.values {
+		if existing == value {
+			return
+		}
+	}
+
+	registry.values = append(registry.values, value)
+
+	// longer secrets first, so that a secret containing a shorter secret is masked completely
+	sort.Slice(registry.values, func(i, j int) bool {
+		return len(registry.values[i]) > len(registry.values[j])
+	})
+
+	var replacements []string
+	for _, secret := range registry.values {
+		replacements = append(replacements, secret, Mask)
+	}
+
+	registry.replacer = strings.NewReplacer(replacements...)
+}
+
+// Redact replaces all registered secrets in the text with the Mask
+func Redact(text string) string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	if registry.replacer == nil {
+		return text
+	}
+
+	return registry.replacer.Replace(text)
+}
+
+// partialSecretSuffixLength returns the length of the longest suffix of the text, which could be the beginning
+// of a registered secret
+func partialSecretSuffixLength(text string) int {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	longest := 0
+
+	for _, secret := range registry.values {
+		for length := min(len(secret)-1, len(text)); length > longest; length-- {
+			if strings.HasSuffix(text, secret[:length]) {
+				longest = length
+				break
+			}
+		}
+	}
+
+	return longest
+}
+
+func hasSecrets() bool {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	return len(registry.values) > 0
+}
@@ -0,0 +1,31 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedactMasksRegisteredSecrets(t *testing.T) {
+	Register("my-super-secret")
+	Register("my-super-secret-with-suffix")
+	Register("abc") // too short, should be ignored
+
+	assert.Equal(t, "login -p=*** done", Redact("login -p=my-super-secret done"))
+	assert.Equal(t, "key *** end", Redact("key my-super-secret-with-suffix end"))
+	assert.Equal(t, "abc", Redact("abc"))
+}
+
+func Test_RedactingWriterMasksSecretsSplitOverMultipleWrites(t *testing.T) {
+	Register("split-secret-value")
+
+	var output strings.Builder
+	writer := NewRedactingWriter(&output)
+
+	writer.Write([]byte("first line\nkey=split-sec"))
+	writer.Write([]byte("ret-value\nlast line ends with split"))
+	writer.Flush()
+
+	assert.Equal(t, "first line\nkey=***\nlast line ends with split", output.String())
+}
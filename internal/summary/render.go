@@ -0,0 +1,161 @@
+package summary
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTable writes the summary as plain text tables, as shown on the console at the end of the run. Empty sections
+// are omitted.
+func (s *Summary) WriteTable(out io.Writer) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, s.title())
+
+	if len(s.Steps) > 0 {
+		fmt.Fprintln(table, "\nSTEP\tDURATION")
+		for _, step := range s.Steps {
+			fmt.Fprintf(table, "%s\t%s\n", step.Name, formatDuration(step.DurationMs))
+		}
+	}
+
+	if len(s.Commands) > 0 {
+		fmt.Fprintln(table, "\nCOMMAND\tEXIT CODE\tDURATION")
+		for _, command := range s.Commands {
+			fmt.Fprintf(table, "%s\t%d\t%s\n", command.Command, command.ExitCode, formatDuration(command.DurationMs))
+		}
+	}
+
+	if len(s.TerraformChanges) > 0 {
+		fmt.Fprintln(table, "\nTERRAFORM PROJECT\tPLAN\tADD\tCHANGE\tDESTROY")
+		for _, changes := range s.TerraformChanges {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\n", changes.Project, changes.planKind(), changes.Add, changes.Change,
+				changes.Destroy)
+		}
+	}
+
+	if len(s.HelmReleases) > 0 {
+		fmt.Fprintln(table, "\nHELM RELEASE\tNAMESPACE")
+		for _, release := range s.HelmReleases {
+			fmt.Fprintf(table, "%s\t%s\n", release.Release, release.Namespace)
+		}
+	}
+
+	if len(s.Warnings) > 0 {
+		fmt.Fprintln(table, "\nWARNINGS")
+		for _, warning := range s.Warnings {
+			fmt.Fprintln(table, warning)
+		}
+	}
+
+	if s.Error != "" {
+		fmt.Fprintln(table, "\nERROR")
+		fmt.Fprintln(table, s.Error)
+	}
+
+	return table.Flush()
+}
+
+// WriteMarkdown writes the summary as Markdown, e.g. to be attached to a pipeline run. Empty sections are omitted.
+func (s *Summary) WriteMarkdown(out io.Writer) error {
+	var markdown strings.Builder
+
+	markdown.WriteString("## " + s.title() + "\n")
+
+	if len(s.Steps) > 0 {
+		markdown.WriteString("\n| Step | Duration |\n|---|---|\n")
+		for _, step := range s.Steps {
+			writeRow(&markdown, step.Name, formatDuration(step.DurationMs))
+		}
+	}
+
+	if len(s.Commands) > 0 {
+		markdown.WriteString("\n| Command | Exit code | Duration |\n|---|---|---|\n")
+		for _, command := range s.Commands {
+			writeRow(&markdown, "`"+command.Command+"`", fmt.Sprint(command.ExitCode), formatDuration(command.DurationMs))
+		}
+	}
+
+	if len(s.TerraformChanges) > 0 {
+		markdown.WriteString("\n| Terraform project | Plan | Add | Change | Destroy |\n|---|---|---|---|---|\n")
+		for _, changes := range s.TerraformChanges {
+			writeRow(&markdown, changes.Project, changes.planKind(), fmt.Sprint(changes.Add), fmt.Sprint(changes.Change),
+				fmt.Sprint(changes.Destroy))
+		}
+	}
+
+	if len(s.HelmReleases) > 0 {
+		markdown.WriteString("\n| Helm release | Namespace |\n|---|---|\n")
+		for _, release := range s.HelmReleases {
+			writeRow(&markdown, release.Release, release.Namespace)
+		}
+	}
+
+	if len(s.Warnings) > 0 {
+		markdown.WriteString("\n### Warnings\n\n")
+		for _, warning := range s.Warnings {
+			markdown.WriteString("- " + singleLine(warning) + "\n")
+		}
+	}
+
+	if s.Error != "" {
+		markdown.WriteString("\n### Error\n\n```\n" + s.Error + "\n```\n")
+	}
+
+	_, err := io.WriteString(out, markdown.String())
+	return err
+}
+
+// title is the headline of the summary, e.g. "Run summary of my-app infrastructure deploy: succeeded after 5m12s"
+func (s *Summary) title() string {
+	program := s.CliCommand
+	if program == "" {
+		program = strings.TrimSpace(s.Program + " " + s.Version)
+	}
+
+	title := "Run summary"
+	if program != "" {
+		title += " of " + program
+	}
+
+	outcome := "succeeded"
+	if s.Error != "" {
+		outcome = "failed"
+	}
+
+	return fmt.Sprintf("%s: %s after %s", title, outcome, formatDuration(s.DurationMs))
+}
+
+func (changes TerraformChanges) planKind() string {
+	if changes.IsDestroy {
+		return "destroy"
+	}
+
+	return "deploy"
+}
+
+func writeRow(markdown *strings.Builder, cells ...string) {
+	for i, cell := range cells {
+		cells[i] = strings.ReplaceAll(singleLine(cell), "|", "\\|")
+	}
+
+	markdown.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+}
+
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// formatDuration rounds to tenths of a second for short durations, and to seconds otherwise (e.g. "1.2s" or "5m12s")
+func formatDuration(milliseconds int64) string {
+	duration := time.Duration(milliseconds) * time.Millisecond
+
+	if duration < time.Minute {
+		return duration.Round(100 * time.Millisecond).String()
+	}
+
+	return duration.Round(time.Second).String()
+}
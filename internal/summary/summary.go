@@ -0,0 +1,198 @@
+// Package summary collects the run summary of an HQ program run: the executed commands, the durations of the recipe
+// steps, the terraform changes, the deployed helm releases, the logged warnings and the final error. Nothing is
+// recorded until the collection is started (done by HQ), so the recipes used without HQ do not collect anything.
+package summary
+
+import (
+	"sync"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/internal/ci"
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+)
+
+// Summary is the run summary of a single program run, as written to the JSON summary file
+type Summary struct {
+	Program    string    `json:"program,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	RunId      string    `json:"runId,omitempty"`
+	CliCommand string    `json:"cliCommand,omitempty"`
+	StartTime  time.Time `json:"startTime"`
+	DurationMs int64     `json:"durationMs"`
+
+	Steps            []Step             `json:"steps"`
+	Commands         []Command          `json:"commands"`
+	TerraformChanges []TerraformChanges `json:"terraformChanges"`
+	HelmReleases     []HelmRelease      `json:"helmReleases"`
+	Warnings         []string           `json:"warnings"`
+
+	// Error is the error the run failed with, empty if the run succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// Step is a recipe step, like "Terraform init" or "Terraform apply"
+type Step struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Command is an executed command, registered secrets are masked
+type Command struct {
+	Command    string `json:"command"`
+	ExitCode   int    `json:"exitCode"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// TerraformChanges are the resource changes of a terraform plan
+type TerraformChanges struct {
+	Project   string `json:"project"`
+	IsDestroy bool   `json:"isDestroy"`
+	Add       int    `json:"add"`
+	Change    int    `json:"change"`
+	Destroy   int    `json:"destroy"`
+}
+
+// HelmRelease is a helm release deployed with the helm recipe
+type HelmRelease struct {
+	Release   string `json:"release"`
+	Namespace string `json:"namespace"`
+}
+
+var (
+	mutex   sync.Mutex
+	started bool
+	current Summary
+)
+
+// Start starts a new collection, previously collected data is dropped
+func Start(program string, version string, runId string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	started = true
+	current = Summary{
+		Program:   program,
+		Version:   version,
+		RunId:     runId,
+		StartTime: time.Now(),
+	}
+}
+
+// Stop stops the collection, the collected data is dropped
+func Stop() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	started = false
+	current = Summary{}
+}
+
+// Finish returns the collected summary of the run, completed with the CLI command, the run duration and the given
+// error. Returns nil if the collection was not started.
+func Finish(cliCommand string, err error) *Summary {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !started {
+		return nil
+	}
+
+	result := current
+	result.CliCommand = cliCommand
+	result.DurationMs = time.Since(current.StartTime).Milliseconds()
+
+	// copies, so that the result is not changed by commands still running in the background
+	result.Steps = append([]Step{}, current.Steps...)
+	result.Commands = append([]Command{}, current.Commands...)
+	result.TerraformChanges = append([]TerraformChanges{}, current.TerraformChanges...)
+	result.HelmReleases = append([]HelmRelease{}, current.HelmReleases...)
+	result.Warnings = append([]string{}, current.Warnings...)
+
+	if err != nil {
+		result.Error = secrets.Redact(err.Error())
+	}
+
+	return &result
+}
+
+// IsEmpty returns true if nothing happened during the run (e.g. if only the help was shown)
+func (s *Summary) IsEmpty() bool {
+	return len(s.Steps) == 0 && len(s.Commands) == 0 && len(s.TerraformChanges) == 0 && len(s.HelmReleases) == 0 &&
+		len(s.Warnings) == 0 && s.Error == ""
+}
+
+// StartStep starts a recipe step, which is shown as collapsible section in CI and recorded with its duration. The
+// returned function ends the step.
+func StartStep(name string) (end func()) {
+	endGroup := ci.StartGroup(name)
+	startTime := time.Now()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			endGroup()
+			record(func() {
+				current.Steps = append(current.Steps, Step{Name: name, DurationMs: time.Since(startTime).Milliseconds()})
+			})
+		})
+	}
+}
+
+// Steps are the consecutive steps of a recipe, starting a step ends the previous one. End has to be called after the
+// last step (usually deferred), so that the step is also ended on early returns.
+type Steps struct {
+	end func()
+}
+
+// Start ends the previous step, and starts the given one
+func (s *Steps) Start(name string) {
+	s.End()
+	s.end = StartStep(name)
+}
+
+// End ends the current step, if any
+func (s *Steps) End() {
+	if s.end != nil {
+		s.end()
+		s.end = nil
+	}
+}
+
+// RecordCommand records an executed command
+func RecordCommand(command string, exitCode int, duration time.Duration) {
+	record(func() {
+		current.Commands = append(current.Commands,
+			Command{Command: command, ExitCode: exitCode, DurationMs: duration.Milliseconds()})
+	})
+}
+
+// RecordTerraformChanges records the resource changes of a terraform plan
+func RecordTerraformChanges(changes TerraformChanges) {
+	record(func() {
+		current.TerraformChanges = append(current.TerraformChanges, changes)
+	})
+}
+
+// RecordHelmRelease records a deployed helm release
+func RecordHelmRelease(release string, namespace string) {
+	record(func() {
+		current.HelmReleases = append(current.HelmReleases, HelmRelease{Release: release, Namespace: namespace})
+	})
+}
+
+// RecordWarning records a logged warning
+func RecordWarning(message string) {
+	record(func() {
+		current.Warnings = append(current.Warnings, message)
+	})
+}
+
+func record(update func()) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if started {
+		update()
+	}
+}
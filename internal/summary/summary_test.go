@@ -0,0 +1,69 @@
+package summary
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NothingIsRecordedUntilStarted(t *testing.T) {
+	Stop()
+	RecordWarning("dropped")
+	StartStep("dropped")()
+	assert.Nil(t, Finish("", nil))
+
+	// Act
+	Start("my-app", "1.0.0", "run-id")
+	t.Cleanup(Stop)
+
+	var steps Steps
+	steps.Start("Terraform init")
+	RecordCommand("terraform init", 0, 1500*time.Millisecond)
+	steps.Start("Terraform plan")
+	steps.End()
+	RecordWarning("sops is outdated")
+
+	runSummary := Finish("my-app infrastructure deploy", errors.New("plan was not approved"))
+
+	// Assert
+	assert.Equal(t, "my-app", runSummary.Program)
+	assert.Equal(t, "my-app infrastructure deploy", runSummary.CliCommand)
+	assert.Len(t, runSummary.Steps, 2)
+	assert.Equal(t, []Command{{Command: "terraform init", ExitCode: 0, DurationMs: 1500}}, runSummary.Commands)
+	assert.Equal(t, []string{"sops is outdated"}, runSummary.Warnings)
+	assert.Equal(t, "plan was not approved", runSummary.Error)
+}
+
+func Test_SummaryIsRenderedAsTableAndMarkdown(t *testing.T) {
+	runSummary := &Summary{
+		CliCommand:       "my-app infrastructure deploy",
+		DurationMs:       312_400,
+		Steps:            []Step{{Name: "Terraform apply", DurationMs: 65_000}},
+		Commands:         []Command{{Command: "az group list | jq", ExitCode: 1, DurationMs: 1_240}},
+		TerraformChanges: []TerraformChanges{{Project: "network", Add: 3, Change: 1}},
+		HelmReleases:     []HelmRelease{{Release: "ingress", Namespace: "ingress-nginx"}},
+	}
+
+	var table strings.Builder
+	var markdown strings.Builder
+
+	// Act
+	tableErr := runSummary.WriteTable(&table)
+	markdownErr := runSummary.WriteMarkdown(&markdown)
+
+	// Assert
+	assert.NoError(t, tableErr)
+	assert.NoError(t, markdownErr)
+
+	assert.Contains(t, table.String(), "Run summary of my-app infrastructure deploy: succeeded after 5m12s\n")
+	assert.Contains(t, table.String(), "Terraform apply  1m5s")
+	assert.Contains(t, table.String(), "network            deploy  3    1       0")
+	assert.NotContains(t, table.String(), "WARNINGS")
+
+	assert.Contains(t, markdown.String(), "## Run summary of my-app infrastructure deploy: succeeded after 5m12s\n")
+	assert.Contains(t, markdown.String(), "| `az group list \\| jq` | 1 | 1.2s |\n")
+	assert.Contains(t, markdown.String(), "| ingress | ingress-nginx |\n")
+}
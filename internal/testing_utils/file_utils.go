@@ -1,12 +1,16 @@
 package testing_utils
 
 import (
+	"github.com/conplementag/cops-hq/v2/internal/logging"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"testing"
 )
 
 func CheckFileContainsString(t *testing.T, fileName string, search string) {
+	// the log files are buffered
+	logging.FlushLogFiles()
+
 	fileContents, err := ioutil.ReadFile(fileName)
 
 	if err != nil {
@@ -17,3 +21,15 @@ func CheckFileContainsString(t *testing.T, fileName string, search string) {
 
 	assert.Contains(t, fileContentsString, search)
 }
+
+func CheckFileDoesNotContainString(t *testing.T, fileName string, search string) {
+	logging.FlushLogFiles()
+
+	fileContents, err := ioutil.ReadFile(fileName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotContains(t, string(fileContents), search)
+}
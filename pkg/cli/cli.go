@@ -2,6 +2,7 @@ package cli
 
 import (
 	"github.com/conplementag/cops-hq/v2/internal"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"os"
@@ -29,6 +30,8 @@ func (cli *cli) AddBaseCommand(use string, shortInfo string, longDescription str
 			}
 		},
 		PreRun: func(cmd *cobra.Command, args []string) {
+			logging.SetCurrentCliCommand(cmd.CommandPath())
+
 			// we have to map the viper parameters on runtime, when the command is executing, to prevent
 			// overwriting of viper mappings in case multiple commands have the same named parameters
 			for _, p := range cw.parameters {
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -64,6 +65,8 @@ func (command *commandWrapper) AddCommand(use string, shortInfo string, longDesc
 			}
 		},
 		PreRun: func(cmd *cobra.Command, args []string) {
+			logging.SetCurrentCliCommand(cmd.CommandPath())
+
 			// we have to map the viper parameters on runtime, when the command is executing, to prevent
 			// overwriting of viper mappings in case multiple commands have the same named parameters
 			for _, p := range cw.parameters {
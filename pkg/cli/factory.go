@@ -47,6 +47,33 @@ func New(programName string, version string) Cli {
 
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 
+	rootCmd.PersistentFlags().BoolP("dry-run", "", false, "Set to only log the commands which would change "+
+		"something, instead of executing them. Read-only commands (queries) are still executed.")
+
+	viper.BindPFlag("dry-run", rootCmd.PersistentFlags().Lookup("dry-run"))
+
+	rootCmd.PersistentFlags().BoolP("non-interactive", "", false, "Set to fail all prompts requiring user "+
+		"input, instead of waiting for it. Detected automatically in CI (CI, TF_BUILD or GITHUB_ACTIONS environment "+
+		"variables) or if no terminal is attached.")
+
+	viper.BindPFlag("non-interactive", rootCmd.PersistentFlags().Lookup("non-interactive"))
+
+	rootCmd.PersistentFlags().BoolP("yes", "", false, "Set to answer all prompts automatically: "+
+		"confirmations are approved, and input prompts use their default value.")
+
+	viper.BindPFlag("yes", rootCmd.PersistentFlags().Lookup("yes"))
+
+	rootCmd.PersistentFlags().String("record-commands", "", "Set to a file path to record all executed "+
+		"commands, with their output and exit codes, into a cassette file. Registered secrets are masked. "+
+		"Useful to reproduce CI runs locally with --replay-commands.")
+
+	viper.BindPFlag("record-commands", rootCmd.PersistentFlags().Lookup("record-commands"))
+
+	rootCmd.PersistentFlags().String("replay-commands", "", "Set to a cassette file recorded with "+
+		"--record-commands to replay the recorded command results, instead of executing the commands.")
+
+	viper.BindPFlag("replay-commands", rootCmd.PersistentFlags().Lookup("replay-commands"))
+
 	rootCmd.PersistentFlags().BoolP("silence-long-running-progress-indicators", "", false, "Set to "+
 		"silence long running operation indicator. Useful for CI.")
 
@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditEntry is a single executed command, as written to the audit file by the audit middleware (see
+// NewAuditMiddleware). The audit file contains one JSON encoded entry per line.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user"`
+
+	// Program and Version of the HQ program which executed the command
+	Program string `json:"program"`
+	Version string `json:"version"`
+
+	// Command is the executed command line, registered secrets are masked
+	Command string `json:"command"`
+	Dir     string `json:"dir"`
+
+	// Subscription is the Azure subscription the command was executed against, if set via the ARM_SUBSCRIPTION_ID
+	// environment variable (like for terraform) or the --subscription argument (like for az)
+	Subscription string `json:"subscription,omitempty"`
+
+	// ExitCode of the command, -1 if the command could not be started, or was killed
+	ExitCode   int   `json:"exitCode"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+type auditMiddleware struct {
+	mutex    sync.Mutex
+	fileName string
+	program  string
+	version  string
+	user     string
+}
+
+// NewAuditMiddleware creates a Middleware, which appends an AuditEntry for every executed command to the given audit
+// file, as one JSON line. Commands skipped in dry-run mode are not executed, and therefore not audited. Since the command
+// is already executed at the time the entry is written, failures to write the audit file are only logged as errors.
+func NewAuditMiddleware(auditFileName string, programName string, version string) Middleware {
+	return &auditMiddleware{
+		fileName: auditFileName,
+		program:  programName,
+		version:  version,
+		user:     currentUserName(),
+	}
+}
+
+func (m *auditMiddleware) Before(ctx context.Context, cmd *exec.Cmd) (context.Context, error) {
+	return ctx, nil
+}
+
+func (m *auditMiddleware) After(ctx context.Context, cmd *exec.Cmd, result *Result, err error) (*Result, error) {
+	entry := AuditEntry{
+		Timestamp:    result.StartTime,
+		User:         m.user,
+		Program:      m.program,
+		Version:      m.version,
+		Command:      result.Command,
+		Dir:          cmd.Dir,
+		Subscription: secrets.Redact(subscriptionOf(cmd)),
+		ExitCode:     result.ExitCode,
+		DurationMs:   result.Duration.Milliseconds(),
+	}
+
+	if entry.Timestamp.IsZero() {
+		// command was not started at all
+		entry.Timestamp = time.Now()
+	}
+
+	if entry.Dir == "" {
+		entry.Dir, _ = os.Getwd()
+	}
+
+	if writeErr := m.write(entry); writeErr != nil {
+		logrus.Errorf("could not write the audit entry for command %s to %s: %v", result.Command, m.fileName, writeErr)
+	}
+
+	return result, err
+}
+
+func (m *auditMiddleware) write(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(m.fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadAuditEntries reads all entries of the given audit file, in the order they were written
+func ReadAuditEntries(auditFileName string) ([]AuditEntry, error) {
+	file, err := os.Open(auditFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("could not parse line %d of the audit file %s: %w", lineNumber, auditFileName, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+func currentUserName() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+
+	return os.Getenv("USERNAME")
+}
+
+// subscriptionOf returns the Azure subscription the command is executed against, if it can be determined
+func subscriptionOf(cmd *exec.Cmd) string {
+	for i, arg := range cmd.Args {
+		if arg == "--subscription" && i+1 < len(cmd.Args) {
+			return cmd.Args[i+1]
+		}
+
+		if value, found := strings.CutPrefix(arg, "--subscription="); found {
+			return value
+		}
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	// last value wins, same as for the executed command
+	subscription := ""
+	for _, variable := range env {
+		if value, found := strings.CutPrefix(variable, "ARM_SUBSCRIPTION_ID="); found {
+			subscription = value
+		}
+	}
+
+	return subscription
+}
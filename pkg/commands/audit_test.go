@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AuditMiddlewareWritesOneEntryPerCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+
+	auditFileName := filepath.Join(t.TempDir(), "audit.jsonl")
+	dir := t.TempDir()
+
+	e := NewCustom(testLogFileName, logger, &ExecutorOptions{
+		Middlewares: []Middleware{NewAuditMiddleware(auditFileName, "my-program", "1.2.3")},
+	})
+	e.RegisterSecret("audited-secret")
+
+	// Act
+	e.Run(context.Background(), "echo audited-secret", WithDir(dir),
+		WithEnv(map[string]string{"ARM_SUBSCRIPTION_ID": "subscription-id"}))
+	e.Run(context.Background(), "sh -c \"exit 4\" --subscription other-subscription")
+
+	// Assert
+	entries, err := ReadAuditEntries(auditFileName)
+	assert.NoError(t, err)
+
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "echo ***", entries[0].Command)
+		assert.Equal(t, dir, entries[0].Dir)
+		assert.Equal(t, "subscription-id", entries[0].Subscription)
+		assert.Equal(t, 0, entries[0].ExitCode)
+		assert.Equal(t, "my-program", entries[0].Program)
+		assert.Equal(t, "1.2.3", entries[0].Version)
+		assert.NotEmpty(t, entries[0].User)
+		assert.False(t, entries[0].Timestamp.IsZero())
+
+		assert.Equal(t, "other-subscription", entries[1].Subscription)
+		assert.Equal(t, 4, entries[1].ExitCode)
+	}
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/spf13/viper"
+)
+
+// cassetteEntry is a single command execution, as recorded in a cassette file (see the global cli flags
+// --record-commands and --replay-commands). Secrets registered via Executor.RegisterSecret are masked in all fields,
+// also if they were registered after the command was recorded.
+type cassetteEntry struct {
+	Command  string `json:"command"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exitCode"`
+}
+
+type cassetteFile struct {
+	Commands []cassetteEntry `json:"commands"`
+}
+
+type cassetteMode int
+
+const (
+	cassetteOff cassetteMode = iota
+	cassetteRecord
+	cassetteReplay
+)
+
+// cassette is shared by all executors using the same file, since HQ programs might create more than one executor
+type cassette struct {
+	mutex    sync.Mutex
+	fileName string
+	entries  []cassetteEntry
+	replayed []bool
+}
+
+var (
+	cassettesMutex sync.Mutex
+	cassettes      = make(map[string]*cassette)
+)
+
+// activeCassette returns the cassette configured via the viper flags "record-commands" and "replay-commands". The flags
+// are read on each execution, so that they can be set via the cli (or viper directly) after the executor was created.
+func activeCassette() (*cassette, cassetteMode, error) {
+	recordFile := viper.GetString("record-commands")
+	replayFile := viper.GetString("replay-commands")
+
+	if recordFile != "" && replayFile != "" {
+		return nil, cassetteOff, errors.New("commands cannot be recorded and replayed at the same time")
+	}
+
+	if recordFile != "" {
+		c, err := openCassette(recordFile, cassetteRecord)
+		return c, cassetteRecord, err
+	}
+
+	if replayFile != "" {
+		c, err := openCassette(replayFile, cassetteReplay)
+		return c, cassetteReplay, err
+	}
+
+	return nil, cassetteOff, nil
+}
+
+func openCassette(fileName string, mode cassetteMode) (*cassette, error) {
+	cassettesMutex.Lock()
+	defer cassettesMutex.Unlock()
+
+	key := fmt.Sprintf("%d:%s", mode, fileName)
+
+	if c, ok := cassettes[key]; ok {
+		return c, nil
+	}
+
+	c := &cassette{fileName: fileName}
+
+	if mode == cassetteReplay {
+		content, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the cassette file %s: %w", fileName, err)
+		}
+
+		var file cassetteFile
+		err = json.Unmarshal(content, &file)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the cassette file %s: %w", fileName, err)
+		}
+
+		c.entries = file.Commands
+		c.replayed = make([]bool, len(file.Commands))
+	}
+
+	cassettes[key] = c
+	return c, nil
+}
+
+// record adds the entry to the cassette. The whole file is written on each record, so that the cassette is complete
+// even if the program fails afterwards (which is usually the run we want to reproduce).
+func (c *cassette) record(entry cassetteEntry) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = append(c.entries, entry)
+
+	// secrets registered since the previous write are masked in the already recorded entries as well
+	for i := range c.entries {
+		c.entries[i].Command = secrets.Redact(c.entries[i].Command)
+		c.entries[i].Stdout = secrets.Redact(c.entries[i].Stdout)
+		c.entries[i].Stderr = secrets.Redact(c.entries[i].Stderr)
+	}
+
+	content, err := json.MarshalIndent(cassetteFile{Commands: c.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// readable for the current user only, since the cassette contains the output of all commands
+	if err := os.WriteFile(c.fileName, content, 0600); err != nil {
+		return err
+	}
+
+	// the permissions of an already existing file are not changed by os.WriteFile
+	return os.Chmod(c.fileName, 0600)
+}
+
+// next returns the first not yet replayed entry for the given command. Same commands are therefore replayed in the
+// order they were recorded.
+func (c *cassette) next(command string) (*cassetteEntry, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, entry := range c.entries {
+		if !c.replayed[i] && entry.Command == command {
+			c.replayed[i] = true
+			return &entry, nil
+		}
+	}
+
+	return nil, fmt.Errorf("command %s was not found in the cassette file %s (or all its recordings were already replayed)",
+		command, c.fileName)
+}
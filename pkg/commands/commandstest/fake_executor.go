@@ -0,0 +1,755 @@
+package commandstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/internal"
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
+	"github.com/stretchr/testify/assert"
+)
+
+// Invocation is a single recorded call on the FakeExecutor
+type Invocation struct {
+	// Method is the name of the called Executor method, e.g. "Execute" or "AskUserToConfirm"
+	Method string
+
+	// Command is the executed command. For methods receiving an *exec.Cmd, the command arguments joined with spaces are
+	// used. For prompts, this is the display message.
+	Command string
+
+	// Dir is the working directory given via commands.WithDir
+	Dir string
+
+	// Env are the environment variables of the command: the ones set via SetEnv, and the ones given via
+	// commands.WithEnv (which take precedence)
+	Env map[string]string
+
+	// Stdin is the input of the command, given via commands.WithStdin or the ...WithInput methods
+	Stdin string
+}
+
+// FakeExecutor is a scripted commands.Executor, which can be used in unit tests to run code depending on the executor
+// (including whole recipe flows like terraform DeployFlow) without executing any real commands. Commands are matched
+// against the configured responses in the order of configuration (exact string, prefix or regex), and every call is
+// recorded, so that it can be verified with the Assert... methods afterwards.
+// Commands which match no response succeed with empty output, unless FailOnUnmatchedCommands is set.
+type FakeExecutor struct {
+	// FailOnUnmatchedCommands lets commands without a matching response fail with an error
+	FailOnUnmatchedCommands bool
+
+	// DefaultConfirmation is the answer to user prompts, once the answers given via AnswerConfirmations are used up
+	DefaultConfirmation bool
+
+	// NonInteractive simulates a program running non-interactively (e.g. in CI): IsInteractive returns false, and
+	// confirmations fail with a *commands.NonInteractiveError in AskUserToConfirmE, once the answers given via
+	// AnswerConfirmations are used up
+	NonInteractive bool
+
+	// Policy is the error policy of the fake (see commands.ExecutorOptions.ErrorPolicy), returned by ErrorPolicy
+	Policy error_handling.Policy
+
+	// trying is set on the views returned by Try, which share the state with the executor they were created from
+	trying bool
+
+	*fakeState
+}
+
+// fakeState are the scripted responses and the recorded calls, shared between the FakeExecutor and its Try views
+type fakeState struct {
+	mutex             sync.Mutex
+	responses         []*Response
+	invocations       []Invocation
+	confirmations     []bool
+	answers           []string
+	registeredSecrets []string
+	inputs            []string
+	env               map[string]string
+}
+
+var _ commands.Executor = (*FakeExecutor)(nil)
+
+// NewFakeExecutor creates a new FakeExecutor without any scripted responses
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{fakeState: &fakeState{}}
+}
+
+// OnCommand adds a response for commands exactly matching the given command
+func (f *FakeExecutor) OnCommand(command string) *Response {
+	return f.addResponse(exactMatcher(command))
+}
+
+// OnPrefix adds a response for commands starting with the given prefix
+func (f *FakeExecutor) OnPrefix(prefix string) *Response {
+	return f.addResponse(prefixMatcher(prefix))
+}
+
+// OnRegex adds a response for commands matching the given regular expression. Panics if the pattern does not compile.
+func (f *FakeExecutor) OnRegex(pattern string) *Response {
+	return f.addResponse(regexMatcher(pattern))
+}
+
+// AnswerConfirmations queues the answers for the next confirmation prompts (AskUserToConfirm...)
+func (f *FakeExecutor) AnswerConfirmations(answers ...bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.confirmations = append(f.confirmations, answers...)
+}
+
+// AnswerPrompts queues the answers for the next user input prompts (AskUserForInput, AskUserForSecret, AskUserToSelect
+// and AskUserToMultiSelect). Answers for selections are the selected choices, multiple choices separated by commas.
+// Once the answers are used up, prompts return their default, or a *commands.NonInteractiveError if there is none.
+func (f *FakeExecutor) AnswerPrompts(answers ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.answers = append(f.answers, answers...)
+}
+
+// Invocations returns all recorded calls, in the order they were made
+func (f *FakeExecutor) Invocations() []Invocation {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]Invocation{}, f.invocations...)
+}
+
+// Commands returns all executed commands (prompts excluded), in the order they were executed
+func (f *FakeExecutor) Commands() []string {
+	var executed []string
+
+	for _, invocation := range f.Invocations() {
+		if !strings.HasPrefix(invocation.Method, "AskUser") {
+			executed = append(executed, invocation.Command)
+		}
+	}
+
+	return executed
+}
+
+// Inputs returns the inputs given to the ...WithInput methods or via commands.WithStdin, in the order of the calls
+func (f *FakeExecutor) Inputs() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]string{}, f.inputs...)
+}
+
+// RegisteredSecrets returns all values registered via RegisterSecret
+func (f *FakeExecutor) RegisteredSecrets() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]string{}, f.registeredSecrets...)
+}
+
+// Env returns all variables set via SetEnv
+func (f *FakeExecutor) Env() map[string]string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return maps.Clone(f.env)
+}
+
+// AssertCalled asserts that the exact command was executed at least once
+func (f *FakeExecutor) AssertCalled(t testing.TB, command string) bool {
+	t.Helper()
+	return assert.Contains(t, f.Commands(), command, "expected command was not executed")
+}
+
+// AssertNotCalled asserts that the exact command was never executed
+func (f *FakeExecutor) AssertNotCalled(t testing.TB, command string) bool {
+	t.Helper()
+	return assert.NotContains(t, f.Commands(), command, "unexpected command was executed")
+}
+
+// AssertCalledTimes asserts that the exact command was executed the given number of times
+func (f *FakeExecutor) AssertCalledTimes(t testing.TB, command string, times int) bool {
+	t.Helper()
+	return assert.Equal(t, times, f.countCommands(exactMatcher(command)), "command %s executed unexpected number of times", command)
+}
+
+// AssertCalledMatching asserts that at least one executed command matches the given regular expression
+func (f *FakeExecutor) AssertCalledMatching(t testing.TB, pattern string) bool {
+	t.Helper()
+	return assert.Positive(t, f.countCommands(regexMatcher(pattern)), "no executed command matches %s", pattern)
+}
+
+// AssertNotCalledMatching asserts that no executed command matches the given regular expression
+func (f *FakeExecutor) AssertNotCalledMatching(t testing.TB, pattern string) bool {
+	t.Helper()
+	return assert.Zero(t, f.countCommands(regexMatcher(pattern)), "an executed command matches %s", pattern)
+}
+
+// AssertCommandOrder asserts that commands matching the given regular expressions were executed in the given order
+// (other commands in between are ignored)
+func (f *FakeExecutor) AssertCommandOrder(t testing.TB, patterns ...string) bool {
+	t.Helper()
+
+	next := 0
+	for _, command := range f.Commands() {
+		if next < len(patterns) && regexp.MustCompile(patterns[next]).MatchString(command) {
+			next++
+		}
+	}
+
+	if next < len(patterns) {
+		return assert.Fail(t, "commands not executed in expected order",
+			"no command matching %s found in expected order; executed commands: %v", patterns[next], f.Commands())
+	}
+
+	return true
+}
+
+// AssertResponsesUsed asserts that all responses limited via Times / Once were used the expected number of times
+func (f *FakeExecutor) AssertResponsesUsed(t testing.TB) bool {
+	t.Helper()
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	result := true
+	for index, response := range f.responses {
+		if response.times > 0 && response.calls != response.times {
+			result = assert.Fail(t, "scripted response not used as expected",
+				"response #%d was used %d times, expected %d times", index+1, response.calls, response.times) && result
+		}
+	}
+
+	return result
+}
+
+func (f *FakeExecutor) Execute(command string) (string, error) {
+	return f.executeString("Execute", context.Background(), command)
+}
+
+func (f *FakeExecutor) ExecuteCmd(cmd *exec.Cmd) (string, error) {
+	return f.executeString("ExecuteCmd", context.Background(), commandOf(cmd))
+}
+
+func (f *FakeExecutor) ExecuteWithProgressInfo(command string) (string, error) {
+	return f.executeString("ExecuteWithProgressInfo", context.Background(), command)
+}
+
+func (f *FakeExecutor) ExecuteCmdWithProgressInfo(cmd *exec.Cmd) (string, error) {
+	return f.executeString("ExecuteCmdWithProgressInfo", context.Background(), commandOf(cmd))
+}
+
+func (f *FakeExecutor) ExecuteWithProgressStep(step string, command string) (string, error) {
+	return f.executeString("ExecuteWithProgressStep", context.Background(), command)
+}
+
+func (f *FakeExecutor) ExecuteSilent(command string) (string, error) {
+	return f.executeString("ExecuteSilent", context.Background(), command)
+}
+
+func (f *FakeExecutor) ExecuteLoud(command string) (string, error) {
+	return f.executeString("ExecuteLoud", context.Background(), command)
+}
+
+func (f *FakeExecutor) ExecuteCmdSilent(cmd *exec.Cmd) (string, error) {
+	return f.executeString("ExecuteCmdSilent", context.Background(), commandOf(cmd))
+}
+
+// ExecuteWithInput reads the whole input, so that it can be checked via Inputs() and Invocation.Stdin
+func (f *FakeExecutor) ExecuteWithInput(command string, input io.Reader) (string, error) {
+	return f.executeString("ExecuteWithInput", context.Background(), command, commands.WithStdin(input))
+}
+
+func (f *FakeExecutor) ExecuteSilentWithInput(command string, input io.Reader) (string, error) {
+	return f.executeString("ExecuteSilentWithInput", context.Background(), command, commands.WithStdin(input))
+}
+
+func (f *FakeExecutor) ExecuteLoudWithInput(command string, input io.Reader) (string, error) {
+	return f.executeString("ExecuteLoudWithInput", context.Background(), command, commands.WithStdin(input))
+}
+
+func (f *FakeExecutor) ExecuteWithProgressInfoAndInput(command string, input io.Reader) (string, error) {
+	return f.executeString("ExecuteWithProgressInfoAndInput", context.Background(), command, commands.WithStdin(input))
+}
+
+func (f *FakeExecutor) ExecuteTTY(command string) error {
+	_, err := f.executeString("ExecuteTTY", context.Background(), command)
+	return err
+}
+
+func (f *FakeExecutor) ExecuteCmdTTY(cmd *exec.Cmd) error {
+	_, err := f.executeString("ExecuteCmdTTY", context.Background(), commandOf(cmd))
+	return err
+}
+
+func (f *FakeExecutor) ExecuteContext(ctx context.Context, command string) (string, error) {
+	return f.executeString("ExecuteContext", ctx, command)
+}
+
+func (f *FakeExecutor) ExecuteCmdContext(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	return f.executeString("ExecuteCmdContext", ctx, commandOf(cmd))
+}
+
+func (f *FakeExecutor) ExecuteWithProgressInfoContext(ctx context.Context, command string) (string, error) {
+	return f.executeString("ExecuteWithProgressInfoContext", ctx, command)
+}
+
+func (f *FakeExecutor) ExecuteCmdWithProgressInfoContext(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	return f.executeString("ExecuteCmdWithProgressInfoContext", ctx, commandOf(cmd))
+}
+
+func (f *FakeExecutor) ExecuteSilentContext(ctx context.Context, command string) (string, error) {
+	return f.executeString("ExecuteSilentContext", ctx, command)
+}
+
+func (f *FakeExecutor) ExecuteLoudContext(ctx context.Context, command string) (string, error) {
+	return f.executeString("ExecuteLoudContext", ctx, command)
+}
+
+func (f *FakeExecutor) ExecuteCmdSilentContext(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	return f.executeString("ExecuteCmdSilentContext", ctx, commandOf(cmd))
+}
+
+func (f *FakeExecutor) ExecuteTTYContext(ctx context.Context, command string) error {
+	_, err := f.executeString("ExecuteTTYContext", ctx, command)
+	return err
+}
+
+func (f *FakeExecutor) ExecuteCmdTTYContext(ctx context.Context, cmd *exec.Cmd) error {
+	_, err := f.executeString("ExecuteCmdTTYContext", ctx, commandOf(cmd))
+	return err
+}
+
+// Run applies the options as far as they are meaningful without a real process: working directory, environment and
+// stdin are recorded (see Invocation), the callbacks and abort patterns are applied to the scripted output, and failed
+// commands are retried (without delay) according to the retry policy
+func (f *FakeExecutor) Run(ctx context.Context, command string, options ...commands.ExecuteOption) (*commands.Result, error) {
+	return f.execute("Run", ctx, command, options...)
+}
+
+// RunCmd applies the options the same way as Run
+func (f *FakeExecutor) RunCmd(ctx context.Context, cmd *exec.Cmd, options ...commands.ExecuteOption) (*commands.Result, error) {
+	return f.execute("RunCmd", ctx, commandOf(cmd), options...)
+}
+
+// ExecuteParallel executes the tasks one after another (in the order of the given tasks), so that the scripted
+// responses are used in a predictable order. Errors are aggregated the same way as by the real executor.
+func (f *FakeExecutor) ExecuteParallel(ctx context.Context, tasks []commands.ParallelTask, settings commands.ParallelSettings) ([]*commands.Result, error) {
+	results := make([]*commands.Result, len(tasks))
+	var taskErrors []error
+
+	for i, task := range tasks {
+		command := task.Command
+		if task.Cmd != nil {
+			command = commandOf(task.Cmd)
+		}
+
+		result, err := f.executeWithOptions("ExecuteParallel", ctx, command, task.Options)
+		results[i] = result
+
+		if err != nil {
+			name := task.Name
+			if name == "" {
+				name = strconv.Itoa(i + 1)
+			}
+
+			taskErrors = append(taskErrors, fmt.Errorf("task %s failed: %w", name, err))
+
+			if settings.FailFast {
+				break
+			}
+		}
+	}
+
+	return results, f.returnErrorOrPanic(errors.Join(taskErrors...))
+}
+
+// ReadOnly returns the FakeExecutor itself, since the fake executes no commands anyway (dry-run mode is not simulated)
+func (f *FakeExecutor) ReadOnly() commands.Executor {
+	return f
+}
+
+// Try returns a view of the FakeExecutor, which shares the responses and the recorded calls, but never panics
+func (f *FakeExecutor) Try() commands.Executor {
+	trying := *f
+	trying.trying = true
+
+	return &trying
+}
+
+func (f *FakeExecutor) ErrorPolicy() error_handling.Policy {
+	if f.trying {
+		return error_handling.Return
+	}
+
+	return f.Policy
+}
+
+func (f *FakeExecutor) RegisterSecret(value string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.registeredSecrets = append(f.registeredSecrets, value)
+}
+
+func (f *FakeExecutor) SetEnv(env map[string]string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.env == nil {
+		f.env = make(map[string]string)
+	}
+
+	maps.Copy(f.env, env)
+}
+
+func (f *FakeExecutor) AskUserToConfirm(displayMessage string) bool {
+	return f.nextConfirmation("AskUserToConfirm", displayMessage)
+}
+
+func (f *FakeExecutor) AskUserToConfirmWithKeyword(displayMessage string, keyword string) bool {
+	return f.nextConfirmation("AskUserToConfirmWithKeyword", displayMessage)
+}
+
+func (f *FakeExecutor) AskUserToConfirmE(displayMessage string) (bool, error) {
+	return f.nextConfirmationE("AskUserToConfirmE", displayMessage)
+}
+
+func (f *FakeExecutor) AskUserToConfirmWithKeywordE(displayMessage string, keyword string) (bool, error) {
+	return f.nextConfirmationE("AskUserToConfirmWithKeywordE", displayMessage)
+}
+
+func (f *FakeExecutor) IsInteractive() bool {
+	return !f.NonInteractive
+}
+
+func (f *FakeExecutor) AskUserForInput(displayMessage string, options *commands.PromptOptions) (string, error) {
+	return f.askUserForText("AskUserForInput", displayMessage, options)
+}
+
+func (f *FakeExecutor) AskUserForSecret(displayMessage string, options *commands.PromptOptions) (string, error) {
+	secret, err := f.askUserForText("AskUserForSecret", displayMessage, options)
+	if err == nil {
+		f.RegisterSecret(secret)
+	}
+
+	return secret, err
+}
+
+func (f *FakeExecutor) AskUserToSelect(displayMessage string, choices []string, options *commands.PromptOptions) (string, error) {
+	answer, err := f.nextAnswer("AskUserToSelect", displayMessage, promptDefault(options))
+	if err == nil && !slices.Contains(choices, answer) {
+		err = fmt.Errorf("answer %s is not one of the choices %v", answer, choices)
+	}
+
+	return answer, f.returnErrorOrPanic(err)
+}
+
+func (f *FakeExecutor) AskUserToMultiSelect(displayMessage string, choices []string, options *commands.PromptOptions) ([]string, error) {
+	defaultSelection := ""
+	if options != nil {
+		defaultSelection = strings.Join(options.DefaultSelection, ",")
+	}
+
+	answer, err := f.nextAnswer("AskUserToMultiSelect", displayMessage, defaultSelection)
+	if err != nil {
+		return nil, f.returnErrorOrPanic(err)
+	}
+
+	selection := strings.Split(answer, ",")
+	for _, choice := range selection {
+		if !slices.Contains(choices, choice) {
+			return nil, f.returnErrorOrPanic(fmt.Errorf("answer %s is not one of the choices %v", choice, choices))
+		}
+	}
+
+	return selection, nil
+}
+
+func (f *FakeExecutor) askUserForText(method string, displayMessage string, options *commands.PromptOptions) (string, error) {
+	answer, err := f.nextAnswer(method, displayMessage, promptDefault(options))
+
+	if err == nil && options != nil && options.Validate != nil {
+		err = options.Validate(answer)
+	}
+
+	return answer, f.returnErrorOrPanic(err)
+}
+
+func (f *FakeExecutor) nextAnswer(method string, displayMessage string, defaultValue string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.invocations = append(f.invocations, Invocation{Method: method, Command: displayMessage})
+
+	if len(f.answers) == 0 {
+		if defaultValue == "" {
+			return "", &commands.NonInteractiveError{Prompt: displayMessage}
+		}
+
+		return defaultValue, nil
+	}
+
+	answer := f.answers[0]
+	f.answers = f.answers[1:]
+
+	if answer == "" {
+		answer = defaultValue
+	}
+
+	return answer, nil
+}
+
+func promptDefault(options *commands.PromptOptions) string {
+	if options == nil {
+		return ""
+	}
+
+	return options.Default
+}
+
+func (f *FakeExecutor) addResponse(matches func(string) bool) *Response {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	response := &Response{matches: matches}
+	f.responses = append(f.responses, response)
+
+	return response
+}
+
+func (f *FakeExecutor) executeString(method string, ctx context.Context, command string, options ...commands.ExecuteOption) (string, error) {
+	result, err := f.execute(method, ctx, command, options...)
+
+	// same as the real executor, a trailing linebreak is not part of the output
+	return strings.TrimSuffix(result.Stdout, "\n"), err
+}
+
+func (f *FakeExecutor) execute(method string, ctx context.Context, command string, options ...commands.ExecuteOption) (*commands.Result, error) {
+	result, err := f.executeWithOptions(method, ctx, command, options)
+	return result, f.returnErrorOrPanic(err)
+}
+
+// executeWithOptions executes the command (see Run for the applied options), without panicking
+func (f *FakeExecutor) executeWithOptions(method string, ctx context.Context, command string, options []commands.ExecuteOption) (*commands.Result, error) {
+	settings := commands.ResolveExecuteOptions(options...)
+
+	var stdin string
+	if settings.Stdin != nil {
+		content, _ := io.ReadAll(settings.Stdin)
+		stdin = string(content)
+	}
+
+	attempts := uint(1)
+	if settings.RetryPolicy != nil && settings.RetryPolicy.Attempts > 1 {
+		attempts = settings.RetryPolicy.Attempts
+	}
+
+	var result *commands.Result
+	var err error
+
+	for attempt := uint(1); attempt <= attempts; attempt++ {
+		invocation := Invocation{Method: method, Command: command, Dir: settings.Dir, Stdin: stdin}
+
+		result, err = f.executeRaw(ctx, invocation, settings)
+		if err == nil {
+			err = applyOutputOptions(result, settings)
+		}
+
+		if err == nil || attempt == attempts || !settings.RetryPolicy.ShouldRetry(ctx, result, err) {
+			break
+		}
+	}
+
+	return result, err
+}
+
+// applyOutputOptions calls the line callbacks with the scripted output, and returns an *commands.AbortedError if a line
+// matches one of the abort patterns
+func applyOutputOptions(result *commands.Result, settings commands.ExecuteSettings) error {
+	streams := []struct {
+		output    string
+		callbacks []func(line string)
+	}{
+		{result.Stdout, settings.StdoutCallbacks},
+		{result.Stderr, settings.StderrCallbacks},
+	}
+
+	for _, stream := range streams {
+		if stream.output == "" {
+			continue
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(stream.output, "\n"), "\n") {
+			for _, callback := range stream.callbacks {
+				callback(line)
+			}
+
+			for _, pattern := range settings.AbortPatterns {
+				if pattern.MatchString(line) {
+					result.ExitCode = -1
+					return &commands.AbortedError{Command: result.Command, Pattern: pattern.String(), Line: line,
+						Stdout: result.Stdout, Stderr: result.Stderr}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *FakeExecutor) executeRaw(ctx context.Context, invocation Invocation, settings commands.ExecuteSettings) (*commands.Result, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	command := invocation.Command
+	invocation.Env = f.envOf(settings)
+	f.invocations = append(f.invocations, invocation)
+
+	if settings.Stdin != nil {
+		f.inputs = append(f.inputs, invocation.Stdin)
+	}
+
+	now := time.Now()
+	result := &commands.Result{
+		Command:   command,
+		StartTime: now,
+		EndTime:   now,
+	}
+
+	if ctx.Err() != nil {
+		result.ExitCode = -1
+		return result, &commands.TimeoutError{Command: command, Cause: ctx.Err()}
+	}
+
+	response := f.findResponse(command)
+
+	if response == nil {
+		if f.FailOnUnmatchedCommands {
+			result.ExitCode = -1
+			return result, fmt.Errorf("no scripted response found for command %s", command)
+		}
+
+		return result, nil
+	}
+
+	response.calls++
+	result.Stdout = response.stdout
+	result.Stderr = response.stderr
+	result.ExitCode = response.exitCode
+
+	if response.err != nil {
+		result.ExitCode = -1
+		return result, response.err
+	}
+
+	if response.exitCode != 0 {
+		// same error as the real executor
+		return result, &commands.ExitError{
+			Command:  command,
+			ExitCode: response.exitCode,
+			Stdout:   response.stdout,
+			Stderr:   response.stderr,
+			Cause:    fmt.Errorf("exit status %d", response.exitCode),
+		}
+	}
+
+	return result, nil
+}
+
+func (f *FakeExecutor) findResponse(command string) *Response {
+	for _, response := range f.responses {
+		if !response.isExhausted() && response.matches(command) {
+			return response
+		}
+	}
+
+	return nil
+}
+
+// envOf returns the environment variables of a command, same as the real executor without the inherited ones
+func (f *FakeExecutor) envOf(settings commands.ExecuteSettings) map[string]string {
+	if len(f.env) == 0 && len(settings.Env) == 0 {
+		return nil
+	}
+
+	env := maps.Clone(settings.Env)
+	if env == nil {
+		env = make(map[string]string)
+	}
+
+	for name, value := range f.env {
+		if _, set := env[name]; !set {
+			env[name] = value
+		}
+	}
+
+	return env
+}
+
+func (f *FakeExecutor) nextConfirmation(method string, displayMessage string) bool {
+	answer, _ := f.nextConfirmationRaw(method, displayMessage)
+	return answer
+}
+
+func (f *FakeExecutor) nextConfirmationE(method string, displayMessage string) (bool, error) {
+	answer, err := f.nextConfirmationRaw(method, displayMessage)
+	return answer, f.returnErrorOrPanic(err)
+}
+
+func (f *FakeExecutor) nextConfirmationRaw(method string, displayMessage string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.invocations = append(f.invocations, Invocation{Method: method, Command: displayMessage})
+
+	if len(f.confirmations) == 0 {
+		if f.NonInteractive {
+			return false, &commands.NonInteractiveError{Prompt: displayMessage}
+		}
+
+		return f.DefaultConfirmation, nil
+	}
+
+	answer := f.confirmations[0]
+	f.confirmations = f.confirmations[1:]
+
+	return answer, nil
+}
+
+func (f *FakeExecutor) countCommands(matches func(string) bool) int {
+	count := 0
+
+	for _, command := range f.Commands() {
+		if matches(command) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// commandOf returns the command arguments joined with spaces, which is easier to match than cmd.String() (which
+// contains the resolved path of the executable)
+func commandOf(cmd *exec.Cmd) string {
+	return strings.Join(cmd.Args, " ")
+}
+
+// returnErrorOrPanic handles the error according to the Policy, like the real executor. Views created by Try() always
+// return the error.
+func (f *FakeExecutor) returnErrorOrPanic(err error) error {
+	if f.trying {
+		return err
+	}
+
+	return internal.ReturnErrorOrPanicWith(f.Policy, err)
+}
@@ -0,0 +1,289 @@
+package commandstest
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResponsesAreMatchedByExactStringPrefixAndRegex(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnCommand("az account show").Returns("account")
+	fake.OnPrefix("terraform plan").Returns("plan")
+	fake.OnRegex(`^helm upgrade \S+`).Returns("upgraded")
+
+	// Act
+	account, _ := fake.Execute("az account show")
+	plan, _ := fake.ExecuteSilent("terraform plan -out=test.tfplan")
+	upgraded, _ := fake.ExecuteCmd(exec.Command("helm", "upgrade", "my-release"))
+	unmatched, err := fake.Execute("az account show --output json")
+
+	// Assert
+	assert.Equal(t, "account", account)
+	assert.Equal(t, "plan", plan)
+	assert.Equal(t, "upgraded", upgraded)
+	assert.Equal(t, "", unmatched)
+	assert.NoError(t, err)
+}
+
+func Test_NonZeroExitCodeReturnsExitError(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnPrefix("terraform plan").Returns("changes").WithStderr("warning").WithExitCode(2)
+
+	// Act
+	output, err := fake.Execute("terraform plan -detailed-exitcode")
+	result, runErr := fake.Run(context.Background(), "terraform plan -detailed-exitcode")
+
+	// Assert
+	assert.Equal(t, "changes", output)
+
+	var exitErr *commands.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 2, exitErr.ExitCode)
+	assert.Contains(t, err.Error(), "Stderr stream: warning")
+
+	assert.Error(t, runErr)
+	assert.Equal(t, 2, result.ExitCode)
+	assert.Equal(t, "warning", result.Stderr)
+}
+
+func Test_ResponsesLimitedByTimesAreUsedInSequence(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnCommand("az storage container create").WithExitCode(1).Times(2)
+	fake.OnCommand("az storage container create").Returns("created")
+
+	// Act
+	_, err1 := fake.Execute("az storage container create")
+	_, err2 := fake.Execute("az storage container create")
+	output, err3 := fake.Execute("az storage container create")
+
+	// Assert
+	assert.Error(t, err1)
+	assert.Error(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, "created", output)
+	fake.AssertCalledTimes(t, "az storage container create", 3)
+	fake.AssertResponsesUsed(t)
+}
+
+func Test_UnmatchedCommandsFailIfConfigured(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.FailOnUnmatchedCommands = true
+
+	// Act
+	_, err := fake.Execute("rm -rf /")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func Test_PanicModeIsHonored(t *testing.T) {
+	// Arrange
+	error_handling.PanicOnAnyError = true
+	defer func() {
+		error_handling.PanicOnAnyError = false
+	}()
+
+	fake := NewFakeExecutor()
+	fake.OnCommand("exit 1").WithExitCode(1)
+
+	// Act & Assert
+	assert.Panics(t, func() {
+		fake.Execute("exit 1")
+	})
+}
+
+func Test_TryViewNeverPanicsAndSharesRecordedCalls(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.Policy = error_handling.Panic
+	fake.OnCommand("exit 1").WithExitCode(1)
+
+	// Act & Assert
+	assert.NotPanics(t, func() {
+		_, err := fake.Try().Execute("exit 1")
+		assert.Error(t, err)
+	})
+	assert.Panics(t, func() {
+		fake.Execute("exit 1")
+	})
+	fake.AssertCalledTimes(t, "exit 1", 2)
+}
+
+func Test_CancelledContextReturnsTimeoutError(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	_, err := fake.ExecuteContext(ctx, "sleep 10")
+
+	// Assert
+	var timeoutErr *commands.TimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func Test_InvocationsConfirmationsAndSecretsAreRecorded(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.AnswerConfirmations(false)
+	fake.DefaultConfirmation = true
+
+	// Act
+	fake.RegisterSecret("my-secret")
+	fake.Execute("first")
+	first := fake.AskUserToConfirm("Continue?")
+	second := fake.AskUserToConfirmWithKeyword("Really continue?", "yes")
+	fake.ExecuteTTY("second")
+
+	// Assert
+	assert.False(t, first)
+	assert.True(t, second)
+	assert.Equal(t, []string{"my-secret"}, fake.RegisteredSecrets())
+	assert.Equal(t, []string{"first", "second"}, fake.Commands())
+	assert.Equal(t, Invocation{Method: "AskUserToConfirm", Command: "Continue?"}, fake.Invocations()[1])
+	fake.AssertCalled(t, "first")
+	fake.AssertNotCalled(t, "third")
+	fake.AssertCalledMatching(t, "^sec")
+	fake.AssertNotCalledMatching(t, "^third")
+	fake.AssertCommandOrder(t, "first", "second")
+}
+
+func Test_ParallelTasksAreExecutedInOrder(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnCommand("helm upgrade a").WithExitCode(1)
+
+	// Act
+	results, err := fake.ExecuteParallel(context.Background(), []commands.ParallelTask{
+		{Name: "a", Command: "helm upgrade a"},
+		{Name: "b", Command: "helm upgrade b"},
+	}, commands.ParallelSettings{FailFast: true})
+
+	// Assert
+	assert.ErrorContains(t, err, "task a failed")
+	assert.Nil(t, results[1])
+	assert.Equal(t, []string{"helm upgrade a"}, fake.Commands())
+}
+
+func Test_InputsAreRecorded(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnPrefix("kubectl apply").Returns("configured")
+
+	// Act
+	output, err := fake.ExecuteSilentWithInput("kubectl apply -f -", strings.NewReader("kind: Namespace"))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "configured", output)
+	assert.Equal(t, []string{"kind: Namespace"}, fake.Inputs())
+	fake.AssertCalled(t, "kubectl apply -f -")
+}
+
+func Test_PromptsAreAnsweredFromQueue(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.AnswerPrompts("blue", "secret-value", "dev,prod")
+
+	// Act
+	color, err1 := fake.AskUserToSelect("Color?", []string{"blue", "green"}, nil)
+	secret, err2 := fake.AskUserForSecret("Password?", nil)
+	environments, err3 := fake.AskUserToMultiSelect("Environments?", []string{"dev", "prod"}, nil)
+	defaulted, err4 := fake.AskUserForInput("Name?", &commands.PromptOptions{Default: "default-name"})
+	_, err5 := fake.AskUserForInput("Region?", nil)
+
+	// Assert
+	assert.NoError(t, errors.Join(err1, err2, err3, err4))
+	assert.Equal(t, "blue", color)
+	assert.Equal(t, "secret-value", secret)
+	assert.Equal(t, []string{"dev", "prod"}, environments)
+	assert.Equal(t, "default-name", defaulted)
+	assert.Equal(t, []string{"secret-value"}, fake.RegisteredSecrets())
+
+	var nonInteractiveErr *commands.NonInteractiveError
+	assert.True(t, errors.As(err5, &nonInteractiveErr))
+}
+
+func Test_RunRecordsDirEnvAndStdin(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.SetEnv(map[string]string{"ARM_SUBSCRIPTION_ID": "subscription", "TF_IN_AUTOMATION": "true"})
+
+	// Act
+	_, err := fake.Run(context.Background(), "terraform apply",
+		commands.WithDir("infrastructure"),
+		commands.WithEnv(map[string]string{"TF_IN_AUTOMATION": "1", "TF_LOG": "DEBUG"}),
+		commands.WithStdin(strings.NewReader("yes")))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []Invocation{{
+		Method:  "Run",
+		Command: "terraform apply",
+		Dir:     "infrastructure",
+		Env:     map[string]string{"ARM_SUBSCRIPTION_ID": "subscription", "TF_IN_AUTOMATION": "1", "TF_LOG": "DEBUG"},
+		Stdin:   "yes",
+	}}, fake.Invocations())
+	assert.Equal(t, []string{"yes"}, fake.Inputs())
+}
+
+func Test_RunCallsCallbacksAndAbortsOnPattern(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnCommand("helm upgrade").Returns("first\nsecond\n").WithStderr("warning")
+	fake.OnCommand("terraform apply").Returns("Acquiring state lock\nError acquiring the state lock\nmore output")
+
+	var stdoutLines, stderrLines []string
+
+	// Act
+	_, err := fake.Run(context.Background(), "helm upgrade",
+		commands.WithStdoutCallback(func(line string) { stdoutLines = append(stdoutLines, line) }),
+		commands.WithStderrCallback(func(line string) { stderrLines = append(stderrLines, line) }))
+	result, abortErr := fake.Run(context.Background(), "terraform apply",
+		commands.WithAbortOnPattern(regexp.MustCompile(`Error acquiring the state lock`)))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, stdoutLines)
+	assert.Equal(t, []string{"warning"}, stderrLines)
+
+	var abortedErr *commands.AbortedError
+	assert.True(t, errors.As(abortErr, &abortedErr))
+	assert.Equal(t, "Error acquiring the state lock", abortedErr.Line)
+	assert.Equal(t, -1, result.ExitCode)
+}
+
+func Test_RunRetriesAccordingToRetryPolicy(t *testing.T) {
+	// Arrange
+	fake := NewFakeExecutor()
+	fake.OnCommand("az storage container create").WithStderr("AuthorizationFailed").WithExitCode(1).Times(2)
+	fake.OnCommand("az storage container create").Returns("created")
+	fake.OnCommand("az group delete").WithStderr("ResourceGroupNotFound").WithExitCode(1)
+
+	policy := commands.RetryPolicy{Attempts: 3, RetryOnStderr: []*regexp.Regexp{commands.AzureAuthorizationFailedPattern}}
+
+	// Act
+	result, err := fake.Run(context.Background(), "az storage container create", commands.WithRetry(policy))
+	_, notRetriedErr := fake.Run(context.Background(), "az group delete", commands.WithRetry(policy))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "created", result.Stdout)
+	assert.Error(t, notRetriedErr)
+	fake.AssertCalledTimes(t, "az storage container create", 3)
+	fake.AssertCalledTimes(t, "az group delete", 1)
+}
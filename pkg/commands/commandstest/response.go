@@ -0,0 +1,82 @@
+package commandstest
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Response is the scripted response of the FakeExecutor for all commands matching the configured rule. Use the
+// builder methods to configure the returned output, for example:
+//
+//	fake.OnPrefix("terraform plan").Returns("plan output").WithExitCode(2)
+type Response struct {
+	matches  func(command string) bool
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error
+	times    int
+	calls    int
+}
+
+// Returns sets the stdout output returned for the matching commands
+func (r *Response) Returns(stdout string) *Response {
+	r.stdout = stdout
+	return r
+}
+
+// WithStderr sets the stderr output of the matching commands. Stderr is only visible via the Result of Run / RunCmd,
+// or as part of the returned error for failing commands (same as with the real executor).
+func (r *Response) WithStderr(stderr string) *Response {
+	r.stderr = stderr
+	return r
+}
+
+// WithExitCode lets the matching commands fail with the given exit code. The returned error is a *commands.ExitError,
+// the same as returned by the real executor.
+func (r *Response) WithExitCode(exitCode int) *Response {
+	r.exitCode = exitCode
+	return r
+}
+
+// WithError lets the matching commands fail with the given error, e.g. to simulate commands which could not be started
+func (r *Response) WithError(err error) *Response {
+	r.err = err
+	return r
+}
+
+// Times limits how often this response is used. After that, the next matching response is used (or the command is
+// handled as unmatched). This can be used to script a sequence of different responses for the same command.
+func (r *Response) Times(times int) *Response {
+	r.times = times
+	return r
+}
+
+// Once is same as Times(1)
+func (r *Response) Once() *Response {
+	return r.Times(1)
+}
+
+func (r *Response) isExhausted() bool {
+	return r.times > 0 && r.calls >= r.times
+}
+
+func exactMatcher(expected string) func(string) bool {
+	return func(command string) bool {
+		return command == expected
+	}
+}
+
+func prefixMatcher(prefix string) func(string) bool {
+	return func(command string) bool {
+		return strings.HasPrefix(command, prefix)
+	}
+}
+
+func regexMatcher(pattern string) func(string) bool {
+	compiled := regexp.MustCompile(pattern)
+
+	return func(command string) bool {
+		return compiled.MatchString(command)
+	}
+}
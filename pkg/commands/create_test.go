@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -28,3 +29,58 @@ func Test_CommandParsing(t *testing.T) {
 		assert.Equal(t, tt.expectedResult, resultCmd.Args)
 	}
 }
+
+func Test_Tokenize(t *testing.T) {
+	tests := []struct {
+		testName       string
+		command        string
+		expectedResult []string
+	}{
+		{"single quotes", "run -a 'some \"stuff\"'", []string{"run", "-a", "some \"stuff\""}},
+		{"escaped quotes within double quotes", "run -a \"say \\\"hi\\\"\"", []string{"run", "-a", "say \"hi\""}},
+		{"escaped space outside quotes", "run some\\ stuff", []string{"run", "some stuff"}},
+		{"quoted value after equal sign", "run --flag=\"a b\" --other='c d'", []string{"run", "--flag=a b", "--other=c d"}},
+		{"quoted value after key", "helm --set image.tag=\"1 2\"", []string{"helm", "--set", "image.tag=1 2"}},
+		{"quotes after equal sign within an argument are kept", "az vm list --query [?name=='x'].id",
+			[]string{"az", "vm", "list", "--query", "[?name=='x'].id"}},
+		{"empty arguments", "run \"\" ''", []string{"run", "", ""}},
+		{"windows paths are kept", "run C:\\temp \"C:\\Program Files\\app\"", []string{"run", "C:\\temp", "C:\\Program Files\\app"}},
+		{"placeholder text is not special", "run {SPACE_WITHIN_QUOTE} {DOUBLE_QUOTE}", []string{"run", "{SPACE_WITHIN_QUOTE}", "{DOUBLE_QUOTE}"}},
+		{"tabs and line breaks separate arguments", "run\ta\nb", []string{"run", "a", "b"}},
+	}
+
+	for _, tt := range tests {
+		fmt.Println("Running test: " + tt.testName)
+		result, err := Tokenize(tt.command)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expectedResult, result)
+		assert.Equal(t, tt.expectedResult, Create(tt.command).Args)
+	}
+}
+
+func Test_TokenizeReturnsParseErrorForUnbalancedQuotes(t *testing.T) {
+	for _, command := range []string{"run -a \"some stuff", "run -a 'some stuff", "run --flag=\"a"} {
+		_, err := Tokenize(command)
+
+		var parseErr *ParseError
+		assert.True(t, errors.As(err, &parseErr), command)
+	}
+
+	// Create stays lenient, the quote is closed at the end of the command
+	assert.Equal(t, []string{"run", "-a", "some stuff"}, Create("run -a \"some stuff").Args)
+}
+
+func Test_TokenizeStrictRejectsAmbiguousInput(t *testing.T) {
+	for _, command := range []string{"run -a object[\"property\"]", "run it's", "run \"a\"b"} {
+		_, err := TokenizeStrict(command)
+		assert.Error(t, err, command)
+
+		_, err = CreateStrict(command)
+		assert.Error(t, err, command)
+	}
+
+	result, err := TokenizeStrict("run -a \"some stuff\" --flag='x'")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"run", "-a", "some stuff", "--flag=x"}, result)
+}
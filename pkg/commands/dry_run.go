@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/spf13/viper"
+)
+
+// IsDryRun returns true if the dry-run mode is active (viper flag "dry-run", set via the global cli flag --dry-run).
+// In dry-run mode, executors only log the commands which are not marked as read-only (see Executor.ReadOnly), instead of
+// executing them. Recipes can use this to skip steps which make no sense without the skipped commands, like waiting for
+// a change to be applied.
+func IsDryRun() bool {
+	return viper.GetBool("dry-run")
+}
+
+// skipInDryRun returns true if the command should not be executed, because dry-run mode is active (viper flag
+// "dry-run") and the command is not read-only. Skipped commands are always logged, so that the user can see what would
+// have been executed.
+func (e *executor) skipInDryRun(command string) bool {
+	if e.readOnly || !IsDryRun() {
+		return false
+	}
+
+	e.logger.Info("[Dry run] Skipping command: " + secrets.Redact(command))
+	return true
+}
+
+func dryRunResult(command string) *Result {
+	now := time.Now()
+
+	return &Result{
+		Command:   secrets.Redact(command),
+		StartTime: now,
+		EndTime:   now,
+	}
+}
@@ -0,0 +1,112 @@
+package commands
+
+import "fmt"
+
+// ExitError is returned when a command exited with a non-zero exit code, also for commands replayed from a cassette.
+// Check the exit code with errors.As(err, &exitErr) and exitErr.ExitCode. Commands which could not be started at all
+// return the error of the start instead, e.g. exec.ErrNotFound (check with errors.Is) if the program is not installed.
+type ExitError struct {
+	// Command is the command which failed
+	Command string
+
+	// ExitCode is the exit code of the command
+	ExitCode int
+
+	// Stdout is the stdout output of the command
+	Stdout string
+
+	// Stderr is the stderr output of the command
+	Stderr string
+
+	// Cause is the *exec.ExitError of the command, or a plain error for replayed commands
+	Cause error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%v; Stderr stream: %s, Stdout stream: %s", e.Cause, e.Stderr, e.Stdout)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Cause
+}
+
+// TimeoutError is returned when a command was killed because its context was cancelled, or its deadline (e.g. the
+// executor default timeout) was exceeded. This makes it possible to distinguish an aborted command from a command
+// which failed on its own (non-zero exit code). The cause is either context.DeadlineExceeded or context.Canceled,
+// which can be checked with errors.Is.
+type TimeoutError struct {
+	// Command is the command which was killed
+	Command string
+
+	// Stdout is the stdout output collected until the command was killed
+	Stdout string
+
+	// Stderr is the stderr output collected until the command was killed
+	Stderr string
+
+	// Cause is the context error which lead to the command being killed
+	Cause error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command %s was killed: %v; Stderr stream: %s, Stdout stream: %s",
+		e.Command, e.Cause, e.Stderr, e.Stdout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Cause
+}
+
+// ParseError is returned when a command string cannot be split into arguments, e.g. because of an unbalanced quote
+// (see Tokenize for the supported grammar).
+type ParseError struct {
+	// Command is the command which could not be parsed
+	Command string
+
+	// Position is the index of the character (rune) in the command, at which the problem was detected
+	Position int
+
+	// Reason describes the problem
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("command %s could not be parsed at position %d: %s", e.Command, e.Position, e.Reason)
+}
+
+// AbortedError is returned when a command was killed, because a line of its output matched an abort pattern (see
+// WithAbortOnPattern)
+type AbortedError struct {
+	// Command is the command which was killed
+	Command string
+
+	// Pattern is the matching abort pattern
+	Pattern string
+
+	// Line is the output line which matched the pattern
+	Line string
+
+	// Stdout is the stdout output collected until the command was killed
+	Stdout string
+
+	// Stderr is the stderr output collected until the command was killed
+	Stderr string
+}
+
+func (e *AbortedError) Error() string {
+	return fmt.Sprintf("command %s was aborted, because the output line %q matched the pattern %s; Stderr stream: %s, Stdout stream: %s",
+		e.Command, e.Line, e.Pattern, e.Stderr, e.Stdout)
+}
+
+// NonInteractiveError is returned by the user prompts (like AskUserForInput or AskUserToConfirmE), if the program runs
+// non-interactively (see Executor.IsInteractive) and the prompt cannot be answered automatically
+type NonInteractiveError struct {
+	// Prompt is the display message of the prompt
+	Prompt string
+}
+
+func (e *NonInteractiveError) Error() string {
+	return fmt.Sprintf("user input is required for the prompt %q, but the program runs non-interactively (CI "+
+		"detected, no terminal attached or --non-interactive set). Use --yes to confirm automatically, or run the "+
+		"program interactively. Input prompts are only answered automatically if they have a default.", e.Prompt)
+}
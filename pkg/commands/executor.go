@@ -1,12 +1,14 @@
 package commands
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"github.com/briandowns/spinner"
 	"github.com/conplementag/cops-hq/v2/internal"
+	"github.com/conplementag/cops-hq/v2/internal/ci"
 	"github.com/conplementag/cops-hq/v2/internal/logging"
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"io"
@@ -29,14 +31,21 @@ type Executor interface {
 	ExecuteCmd(cmd *exec.Cmd) (output string, err error)
 
 	// ExecuteWithProgressInfo is same as Execute, except an infinite progress bar is shown, signaling an async operation to
-	// the user. The progress bar can be overridden with Viper parameter "silence-long-running-progress-indicators" - useful
-	// for CI for example.
+	// the user. The progress bar shows the running command and the elapsed time, and a completion line with the duration
+	// is written to the log file. The progress bar can be overridden with Viper parameter
+	// "silence-long-running-progress-indicators" - useful for CI for example. In this case, a "still running" line is
+	// logged every minute instead, so that build agents do not kill the job because of inactivity.
 	ExecuteWithProgressInfo(command string) (output string, err error)
 
 	// ExecuteCmdWithProgressInfo is same as ExecuteWithProgressInfo, except you can provide the os/exec command directly. Useful to avoid Executor escaping logic,
 	// in rare cases where the command does not follow the usual --argument value semantics.
 	ExecuteCmdWithProgressInfo(cmd *exec.Cmd) (output string, err error)
 
+	// ExecuteWithProgressStep is same as ExecuteWithProgressInfo, except the progress bar shows the given step description
+	// instead of the command (e.g. "Waiting for the helm release my-app"), making it easy to tell which of multiple long
+	// running operations is still running
+	ExecuteWithProgressStep(step string, command string) (output string, err error)
+
 	// ExecuteSilent will run the given command, returning the stdout output and errors (if any).
 	// No command output is shown on the console or logged to the file (irrelevant of the chatty / quiet setting). Can be
 	// used for commands that are too verbose and clutter the output.
@@ -53,6 +62,23 @@ type Executor interface {
 	// in rare cases where the command does not follow the usual --argument value semantics.
 	ExecuteCmdSilent(cmd *exec.Cmd) (output string, err error)
 
+	// ExecuteWithInput is same as Execute, except the given input is piped into the stdin of the command (e.g. a
+	// manifest for kubectl apply -f -). This avoids writing sensitive data to temporary files. The input is never logged
+	// (nor recorded in cassette files), so it is safe to pass secrets this way.
+	ExecuteWithInput(command string, input io.Reader) (output string, err error)
+
+	// ExecuteSilentWithInput is same as ExecuteSilent, except the given input is piped into the stdin of the command (see
+	// ExecuteWithInput)
+	ExecuteSilentWithInput(command string, input io.Reader) (output string, err error)
+
+	// ExecuteLoudWithInput is same as ExecuteLoud, except the given input is piped into the stdin of the command (see
+	// ExecuteWithInput)
+	ExecuteLoudWithInput(command string, input io.Reader) (output string, err error)
+
+	// ExecuteWithProgressInfoAndInput is same as ExecuteWithProgressInfo, except the given input is piped into the stdin
+	// of the command (see ExecuteWithInput)
+	ExecuteWithProgressInfoAndInput(command string, input io.Reader) (output string, err error)
+
 	// ExecuteTTY is a special executor for cases where the called command needs to detect it runs in a TTY session.
 	// One example of such command is Docker. Commands executed via ExecuteTTY have their output shown on the console,
 	// but the output is NOT saved to a log file. Chatty / Quiet settings have no effect on this method.
@@ -62,246 +88,843 @@ type Executor interface {
 	// in rare cases where the command does not follow the usual --argument value semantics.
 	ExecuteCmdTTY(cmd *exec.Cmd) error
 
+	// ExecuteContext is same as Execute, except the command is bound to the given context. When the context is cancelled
+	// or its deadline is exceeded, the command (including all processes it started) is killed, and a *TimeoutError is
+	// returned. Output produced until then is still written to the log file as usual.
+	ExecuteContext(ctx context.Context, command string) (output string, err error)
+
+	// ExecuteCmdContext is same as ExecuteCmd, except the command is bound to the given context (see ExecuteContext).
+	ExecuteCmdContext(ctx context.Context, cmd *exec.Cmd) (output string, err error)
+
+	// ExecuteWithProgressInfoContext is same as ExecuteWithProgressInfo, except the command is bound to the given context
+	// (see ExecuteContext).
+	ExecuteWithProgressInfoContext(ctx context.Context, command string) (output string, err error)
+
+	// ExecuteCmdWithProgressInfoContext is same as ExecuteCmdWithProgressInfo, except the command is bound to the given
+	// context (see ExecuteContext).
+	ExecuteCmdWithProgressInfoContext(ctx context.Context, cmd *exec.Cmd) (output string, err error)
+
+	// ExecuteSilentContext is same as ExecuteSilent, except the command is bound to the given context (see ExecuteContext).
+	ExecuteSilentContext(ctx context.Context, command string) (output string, err error)
+
+	// ExecuteLoudContext is same as ExecuteLoud, except the command is bound to the given context (see ExecuteContext).
+	ExecuteLoudContext(ctx context.Context, command string) (output string, err error)
+
+	// ExecuteCmdSilentContext is same as ExecuteCmdSilent, except the command is bound to the given context
+	// (see ExecuteContext).
+	ExecuteCmdSilentContext(ctx context.Context, cmd *exec.Cmd) (output string, err error)
+
+	// ExecuteTTYContext is same as ExecuteTTY, except the command is bound to the given context. Since the command owns
+	// the terminal, only the started process itself is killed on cancellation, not the processes it started.
+	ExecuteTTYContext(ctx context.Context, command string) error
+
+	// ExecuteCmdTTYContext is same as ExecuteCmdTTY, except the command is bound to the given context
+	// (see ExecuteTTYContext).
+	ExecuteCmdTTYContext(ctx context.Context, cmd *exec.Cmd) error
+
+	// Run executes the given command, and returns a structured Result containing the stdout and stderr output, the exit
+	// code and timing information. Per default, Run behaves like ExecuteContext regarding the console and log file output,
+	// which can be changed via options (e.g. WithSilentOutput, WithLoudOutput or WithProgressInfo). The Result is also
+	// returned in case of errors, so that decisions can be made based on the exit code or the stderr content. Exit code is
+	// -1 if the command could not be started or was killed.
+	Run(ctx context.Context, command string, options ...ExecuteOption) (*Result, error)
+
+	// RunCmd is same as Run, except you can provide the os/exec command directly. Useful to avoid Executor escaping logic,
+	// in rare cases where the command does not follow the usual --argument value semantics.
+	RunCmd(ctx context.Context, cmd *exec.Cmd, options ...ExecuteOption) (*Result, error)
+
+	// ExecuteParallel executes the given tasks concurrently, and returns their results in the same order as the tasks.
+	// Results of tasks which were not started (see ParallelSettings.FailFast) are nil. The console and log file output
+	// of the tasks is not interleaved: it is either written as one labelled block per task once the task finished, or
+	// line by line with the task name as prefix (see ParallelSettings.StreamOutput). Errors of all failed tasks are
+	// aggregated into the returned error (use errors.As / errors.Is to check for specific errors).
+	ExecuteParallel(ctx context.Context, tasks []ParallelTask, settings ParallelSettings) ([]*Result, error)
+
+	// ReadOnly returns a view of this executor, which marks all commands executed through it as read-only queries (like
+	// az account show or terraform output). Read-only commands are executed in dry-run mode as well (viper flag "dry-run"),
+	// all other commands are only logged and skipped with an empty result.
+	ReadOnly() Executor
+
+	// Try returns a view of this executor for commands which are expected to fail (like checking if an optional tool is
+	// installed). Errors of commands executed through it are always returned, never raised as panic, and not annotated
+	// as errors of the CI run. Can be combined with ReadOnly.
+	Try() Executor
+
+	// ErrorPolicy returns the error policy of this executor, see ExecutorOptions.ErrorPolicy. Recipes using the executor
+	// raise their own errors with the same policy.
+	ErrorPolicy() error_handling.Policy
+
+	// RegisterSecret registers a secret value (like a password or an access key), which will be masked with *** in the
+	// command echo lines, in the command output written to the console and the log file, in all log messages and in the
+	// returned error messages. Values returned as command output are not masked. Secrets are registered process wide
+	// (logging is global as well), so they apply to all executors. Values shorter than 4 characters are ignored.
+	// Note: output of TTY commands is passed to the terminal directly, and cannot be masked.
+	RegisterSecret(value string)
+
+	// SetEnv sets environment variables for all following commands of this executor (including the views returned by
+	// ReadOnly and Try), without changing the environment of the current process. Used by the azure_login recipe to
+	// pass the credentials to terraform. Variables given via WithEnv take precedence.
+	SetEnv(env map[string]string)
+
+	// IsInteractive returns false if the prompts cannot be answered by a user: if a CI environment is detected (see IsCI),
+	// no terminal is attached to stdin, or one of the viper flags "non-interactive" or "yes" is set. Input overridden
+	// via OverrideStdIn is always considered interactive, unless one of the flags is set.
+	IsInteractive() bool
+
 	// AskUserToConfirm pauses the execution, and awaits for user to confirm (by either typing yes, Y or y).
-	// Parameter displayMessage can be used to show a message on the screen.
+	// Parameter displayMessage can be used to show a message on the screen. Confirmation is given automatically if
+	// the viper flag "yes" is set. If the program runs non-interactively (see IsInteractive), an error naming the prompt
+	// is logged, and false is returned. Use AskUserToConfirmE to get the error instead.
 	AskUserToConfirm(displayMessage string) bool
 
 	// AskUserToConfirmWithKeyword pauses the execution, and awaits for user to confirm with the requested keyword.
-	// Parameter displayMessage can be used to show a message on the screen.
+	// Parameter displayMessage can be used to show a message on the screen. Non-interactive behaviour is the same as
+	// for AskUserToConfirm.
 	AskUserToConfirmWithKeyword(displayMessage string, keyword string) bool
+
+	// AskUserToConfirmE is same as AskUserToConfirm, but returns a *NonInteractiveError if the program runs
+	// non-interactively (see IsInteractive) and the viper flag "yes" is not set, instead of silently declining.
+	AskUserToConfirmE(displayMessage string) (bool, error)
+
+	// AskUserToConfirmWithKeywordE is same as AskUserToConfirmWithKeyword, but returns a *NonInteractiveError if the
+	// program runs non-interactively (see AskUserToConfirmE).
+	AskUserToConfirmWithKeywordE(displayMessage string, keyword string) (bool, error)
+
+	// AskUserForInput pauses the execution, and awaits for user to enter a line of text. Parameter displayMessage is shown
+	// on the screen. Options can define a default (used for empty input) and a validation function (user is asked again
+	// for invalid input), see PromptOptions. If the program runs non-interactively (see IsInteractive), the default is
+	// returned without asking, or a *NonInteractiveError if there is no default.
+	AskUserForInput(displayMessage string, options *PromptOptions) (string, error)
+
+	// AskUserForSecret is same as AskUserForInput, but the input is not echoed on the terminal, and the entered value is
+	// registered as a secret (see RegisterSecret).
+	AskUserForSecret(displayMessage string, options *PromptOptions) (string, error)
+
+	// AskUserToSelect pauses the execution, and awaits for user to select one of the given choices, either by the number
+	// shown in front of the choice, or the choice itself. Defaults and non-interactive behaviour are the same as for
+	// AskUserForInput.
+	AskUserToSelect(displayMessage string, choices []string, options *PromptOptions) (string, error)
+
+	// AskUserToMultiSelect is same as AskUserToSelect, but the user can select multiple choices, separated by commas.
+	// At least one choice has to be selected. Use PromptOptions.DefaultSelection to define the defaults.
+	AskUserToMultiSelect(displayMessage string, choices []string, options *PromptOptions) ([]string, error)
 }
 
 type executor struct {
-	logFileName string
-	logger      *logrus.Logger
-	chatty      bool
+	logFileName    string
+	logger         *logrus.Logger
+	chatty         bool
+	defaultTimeout time.Duration
+	readOnly       bool
+	trying         bool
+	errorPolicy    error_handling.Policy
+
+	strictCommandParsing bool
+	middlewares          []Middleware
+
+	// env is shared with the ReadOnly and Try views
+	env *executorEnv
 
 	stdin io.Reader
 }
 
 func (e *executor) Execute(command string) (output string, err error) {
-	return e.executeString(command, false, false)
+	return e.ExecuteContext(context.Background(), command)
 }
 
 func (e *executor) ExecuteCmd(cmd *exec.Cmd) (output string, err error) {
-	return e.executeCmd(cmd, false)
+	return e.ExecuteCmdContext(context.Background(), cmd)
 }
 
 func (e *executor) ExecuteWithProgressInfo(command string) (output string, err error) {
-	if !viper.GetBool("silence-long-running-progress-indicators") {
-		spinner := createAndStartSpinner()
-		defer spinner.Stop()
-	}
-
-	return e.executeString(command, false, false)
+	return e.ExecuteWithProgressInfoContext(context.Background(), command)
 }
 
 func (e *executor) ExecuteCmdWithProgressInfo(cmd *exec.Cmd) (output string, err error) {
-	if !viper.GetBool("silence-long-running-progress-indicators") {
-		spinner := createAndStartSpinner()
-		defer spinner.Stop()
-	}
+	return e.ExecuteCmdWithProgressInfoContext(context.Background(), cmd)
+}
 
-	return e.executeCmd(cmd, false)
+func (e *executor) ExecuteWithProgressStep(step string, command string) (output string, err error) {
+	return outputOf(e.Run(context.Background(), command, WithProgressStep(step)))
 }
 
 func (e *executor) ExecuteSilent(command string) (output string, err error) {
-	return e.executeString(command, true, false)
+	return e.ExecuteSilentContext(context.Background(), command)
 }
 
 func (e *executor) ExecuteLoud(command string) (output string, err error) {
-	return e.executeString(command, false, true)
+	return e.ExecuteLoudContext(context.Background(), command)
 }
 
 func (e *executor) ExecuteCmdSilent(cmd *exec.Cmd) (output string, err error) {
-	return e.executeCmd(cmd, true)
+	return e.ExecuteCmdSilentContext(context.Background(), cmd)
 }
 
-func (e *executor) ExecuteTTY(command string) error {
-	e.logger.Info("[Command] " + command)
-	cmd := Create(command)
+func (e *executor) ExecuteWithInput(command string, input io.Reader) (output string, err error) {
+	return outputOf(e.Run(context.Background(), command, WithStdin(input)))
+}
 
-	// only the direct pipe to os.Std* will work for TTY, using io.MultiWriter like in
-	// the standard Execute() did not work that executing process recognizes it is in TTY session...
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func (e *executor) ExecuteSilentWithInput(command string, input io.Reader) (output string, err error) {
+	return outputOf(e.Run(context.Background(), command, WithSilentOutput(), WithStdin(input)))
+}
 
-	err := cmd.Run()
+func (e *executor) ExecuteLoudWithInput(command string, input io.Reader) (output string, err error) {
+	return outputOf(e.Run(context.Background(), command, WithLoudOutput(), WithStdin(input)))
+}
 
-	return internal.ReturnErrorOrPanic(err)
+func (e *executor) ExecuteWithProgressInfoAndInput(command string, input io.Reader) (output string, err error) {
+	return outputOf(e.Run(context.Background(), command, WithProgressInfo(), WithStdin(input)))
+}
+
+func (e *executor) ExecuteTTY(command string) error {
+	return e.ExecuteTTYContext(context.Background(), command)
 }
 
 func (e *executor) ExecuteCmdTTY(cmd *exec.Cmd) error {
-	e.logger.Info("[Command] " + cmd.String())
+	return e.ExecuteCmdTTYContext(context.Background(), cmd)
+}
+
+func (e *executor) ExecuteContext(ctx context.Context, command string) (output string, err error) {
+	return outputOf(e.Run(ctx, command))
+}
+
+func (e *executor) ExecuteCmdContext(ctx context.Context, cmd *exec.Cmd) (output string, err error) {
+	return outputOf(e.RunCmd(ctx, cmd))
+}
+
+func (e *executor) ExecuteWithProgressInfoContext(ctx context.Context, command string) (output string, err error) {
+	return outputOf(e.Run(ctx, command, WithProgressInfo()))
+}
+
+func (e *executor) ExecuteCmdWithProgressInfoContext(ctx context.Context, cmd *exec.Cmd) (output string, err error) {
+	return outputOf(e.RunCmd(ctx, cmd, WithProgressInfo()))
+}
+
+func (e *executor) ExecuteSilentContext(ctx context.Context, command string) (output string, err error) {
+	return outputOf(e.Run(ctx, command, WithSilentOutput()))
+}
+
+func (e *executor) ExecuteLoudContext(ctx context.Context, command string) (output string, err error) {
+	return outputOf(e.Run(ctx, command, WithLoudOutput()))
+}
+
+func (e *executor) ExecuteCmdSilentContext(ctx context.Context, cmd *exec.Cmd) (output string, err error) {
+	return outputOf(e.RunCmd(ctx, cmd, WithSilentOutput()))
+}
+
+func (e *executor) ExecuteTTYContext(ctx context.Context, command string) error {
+	if e.skipInDryRun(command) {
+		return nil
+	}
+
+	cmd, err := e.createCommand(command)
+	if err != nil {
+		return e.returnErrorOrPanic(err)
+	}
+
+	return e.returnErrorOrPanic(e.runTTY(ctx, command, cmd))
+}
+
+func (e *executor) ExecuteCmdTTYContext(ctx context.Context, cmd *exec.Cmd) error {
+	if e.skipInDryRun(cmd.String()) {
+		return nil
+	}
+
+	return e.returnErrorOrPanic(e.runTTY(ctx, cmd.String(), cmd))
+}
+
+// runTTY is the non-panicking implementation of ExecuteTTYContext and ExecuteCmdTTYContext
+func (e *executor) runTTY(ctx context.Context, command string, cmd *exec.Cmd) error {
+	settings := &executeSettings{}
+	e.env.addTo(settings)
+	settings.applyTo(cmd)
+
+	_, err := e.withMiddlewares(ctx, command, cmd, func(ctx context.Context, command string) (*Result, error) {
+		e.logger.Info("[Command] " + secrets.Redact(command))
+		return e.executeTTY(ctx, cmd, command)
+	})
+
+	return err
+}
+
+func (e *executor) Run(ctx context.Context, command string, options ...ExecuteOption) (*Result, error) {
+	result, err := e.run(ctx, command, nil, newExecuteSettings(options))
+	return result, e.returnErrorOrPanic(err)
+}
+
+func (e *executor) RunCmd(ctx context.Context, cmd *exec.Cmd, options ...ExecuteOption) (*Result, error) {
+	result, err := e.run(ctx, "", cmd, newExecuteSettings(options))
+	return result, e.returnErrorOrPanic(err)
+}
+
+// runOnce executes the command a single time, see run
+func (e *executor) runOnce(ctx context.Context, command string, cmd *exec.Cmd, settings *executeSettings) (*Result, error) {
+	commandStartMessage := "[Command (via os/exec)] "
+
+	if cmd == nil {
+		var err error
+		commandStartMessage = "[Command] "
+
+		cmd, err = e.createCommand(command)
+		if err != nil {
+			return &Result{Command: secrets.Redact(command), ExitCode: -1}, err
+		}
+	} else {
+		command = cmd.String()
+	}
+
+	if e.skipInDryRun(command) {
+		return dryRunResult(command), nil
+	}
+
+	if settings.taskName != "" {
+		commandStartMessage += "[" + settings.taskName + "] "
+	}
+
+	e.env.addTo(settings)
+	settings.applyTo(cmd)
+
+	return e.withMiddlewares(ctx, command, cmd, func(ctx context.Context, command string) (*Result, error) {
+		// in CI, the output of every command is shown as collapsible section (except for parallel tasks, since the
+		// sections cannot be interleaved)
+		if e.showsOutputOnConsole(settings) && settings.taskName == "" {
+			endGroup := ci.StartGroup(command)
+			defer endGroup()
+		}
+
+		e.logCommandStart(commandStartMessage+command, command, cmd, settings)
+
+		if !settings.progressInfo {
+			return e.execute(ctx, cmd, command, settings)
+		}
+
+		progress := e.startProgress(settings.progressStepOf(cmd.Args))
+		result, err := e.execute(ctx, cmd, command, settings)
+		progress.finish(err)
+
+		return result, err
+	})
+}
+
+// createCommand splits the command string into the os/exec command. Unbalanced quotes are always rejected, ambiguous
+// input only if strict command parsing is enabled.
+func (e *executor) createCommand(command string) (*exec.Cmd, error) {
+	split := Tokenize
+	if e.strictCommandParsing {
+		split = TokenizeStrict
+	}
+
+	commandParts, err := split(command)
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.Command = secrets.Redact(parseErr.Command)
+		}
+
+		return nil, err
+	}
+
+	return commandFromParts(commandParts), nil
+}
+
+// executeTTY is the non-panicking core of the TTY command executions. Stdout and Stderr of the result are always empty,
+// since the output is passed to the terminal directly.
+func (e *executor) executeTTY(ctx context.Context, cmd *exec.Cmd, displayCommand string) (*Result, error) {
+	result := &Result{
+		Command:  secrets.Redact(displayCommand),
+		ExitCode: -1,
+	}
+
+	if err := refuseDuringShutdown(result.Command); err != nil {
+		return result, err
+	}
+
+	cassette, cassetteMode, err := activeCassette()
+	if err != nil {
+		return result, err
+	}
+
+	cassetteCommand := secrets.Redact(strings.Join(cmd.Args, " "))
+
+	// output of TTY commands is not captured, so only the exit code is recorded and replayed
+	if cassetteMode == cassetteReplay {
+		return result, replayCommand(cassette, cassetteCommand, io.Discard, io.Discard, result)
+	}
+
+	ctx, cancel := e.withDefaultTimeout(ctx)
+	defer cancel()
 
 	// only the direct pipe to os.Std* will work for TTY, using io.MultiWriter like in
 	// the standard Execute() did not work that executing process recognizes it is in TTY session...
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	err := cmd.Run()
+	result.StartTime = time.Now()
+	err = cmd.Start()
 
-	return internal.ReturnErrorOrPanic(err)
-}
+	if err != nil {
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+
+		if cassetteMode == cassetteRecord {
+			recordCommand(cassette, cassetteCommand, "", err.Error(), -1)
+		}
 
-func (e *executor) executeString(command string, silent bool, loud bool) (output string, err error) {
-	if silent && loud {
-		return "", errors.New("it makes no sense to have a command execute as both silent and loud")
+		return result, err
 	}
 
-	if !silent {
-		commandStartMessage := "[Command] " + command
+	// TTY commands are not moved into an own process group (it would detach them from the terminal), so only the
+	// started process can be killed here
+	stopWatching := watchContext(ctx, func() {
+		cmd.Process.Kill()
+	})
+	untrack := trackRunningCommand(cmd)
 
-		if e.chatty || loud {
-			e.logger.Info(commandStartMessage)
-		} else {
-			logging.NewLogFileAppender(e.logFileName).Write([]byte(commandStartMessage))
-		}
+	err = cmd.Wait()
+	untrack()
+	stopWatching()
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.ExitCode = cmd.ProcessState.ExitCode()
+
+	if cassetteMode == cassetteRecord {
+		recordCommand(cassette, cassetteCommand, "", "", result.ExitCode)
+	}
+
+	var exitErr *exec.ExitError
+
+	if err != nil && ctx.Err() != nil {
+		err = &TimeoutError{Command: secrets.Redact(cmd.String()), Cause: ctx.Err()}
+	} else if errors.As(err, &exitErr) {
+		// the output went to the terminal directly, so there is no output to add
+		err = &ExitError{Command: result.Command, ExitCode: exitErr.ExitCode(), Cause: err}
+	}
+
+	if err != nil && isShuttingDown() {
+		err = fmt.Errorf("%w: %w", ErrInterrupted, err)
 	}
 
-	cmd := Create(command)
-	return e.execute(cmd, silent, loud)
+	return result, err
 }
 
-func (e *executor) executeCmd(cmd *exec.Cmd, silent bool) (output string, err error) {
-	if !silent {
-		commandStartMessage := "[Command (via os/exec)] " + cmd.String()
+func (e *executor) logCommandStart(commandStartMessage string, command string, cmd *exec.Cmd, settings *executeSettings) {
+	if settings.silent {
+		return
+	}
 
-		if e.chatty {
-			e.logger.Info(commandStartMessage)
-		} else {
-			logging.NewLogFileAppender(e.logFileName).Write([]byte(commandStartMessage))
-		}
+	if cmd.Dir != "" {
+		commandStartMessage += " (in " + cmd.Dir + ")"
+	}
+
+	commandStartMessage = secrets.Redact(commandStartMessage)
+
+	if e.chatty || settings.loud {
+		e.logger.Info(commandStartMessage)
+	} else if e.logFileName != "" {
+		logging.SharedLogFile(e.logFileName).WriteLine(commandStartMessage, secrets.Redact(command))
 	}
+}
 
-	return e.execute(cmd, silent, false)
+// showsOutputOnConsole returns true if the command output is shown on the console, not only written to the log file
+func (e *executor) showsOutputOnConsole(settings *executeSettings) bool {
+	return !settings.silent && (e.chatty || viper.GetBool("verbose") || settings.loud)
 }
 
-// execute argument logic is as follows:
+// execute is the non-panicking core of all command executions. Argument logic is as follows:
 // if silent is given, the command output will be suppressed from automatic console / file logging
 // if loud is given, the command output will be explicitly outputted, even in non-chatty mode (useful for login or similar)
 // both silent and loud make so sense at the same time
-func (e *executor) execute(cmd *exec.Cmd, silent bool, loud bool) (output string, err error) {
-	if silent && loud {
-		return "", errors.New("it makes no sense to have a command execute as both silent and loud")
+func (e *executor) execute(ctx context.Context, cmd *exec.Cmd, displayCommand string, settings *executeSettings) (*Result, error) {
+	result := &Result{
+		Command:  secrets.Redact(displayCommand),
+		ExitCode: -1,
 	}
 
-	// Logic of conditional pipe-ing of command outputs here:
-	// 1. We "capture" the sources of command output from the command itself, by assigning the pipes to local variables.
-	//    These variables are of type io.Reader.
-	cmdStdOut, pipeError := cmd.StdoutPipe()
-	if pipeError != nil {
-		return "", internal.ReturnErrorOrPanic(pipeError)
+	if settings.silent && settings.loud {
+		return result, errors.New("it makes no sense to have a command execute as both silent and loud")
 	}
 
-	cmdStdErr, pipeError := cmd.StderrPipe()
-	if pipeError != nil {
-		return "", internal.ReturnErrorOrPanic(pipeError)
+	if err := refuseDuringShutdown(result.Command); err != nil {
+		return result, err
 	}
 
-	// Start command
-	commandStartError := cmd.Start()
+	cassette, cassetteMode, err := activeCassette()
+	if err != nil {
+		return result, err
+	}
 
-	if commandStartError != nil {
-		return "", internal.ReturnErrorOrPanic(commandStartError)
+	cassetteCommand := secrets.Redact(strings.Join(cmd.Args, " "))
+
+	ctx, cancel := e.withDefaultTimeout(ctx)
+	defer cancel()
+
+	// the abort is done via the context, so that the process tree is killed the same way as on timeouts
+	abort := context.CancelCauseFunc(func(error) {})
+	if len(settings.abortPatterns) > 0 {
+		ctx, abort = context.WithCancelCause(ctx)
+		defer abort(nil)
 	}
 
-	// 2. We create a composite io.Writer consisting of multiple sinks. Depending on the configuration, these writers
+	// 1. We create a composite io.Writer consisting of multiple sinks. Depending on the configuration, these writers
 	//    either write to "nothing" (discard), or they write to a file / console / buffer to collect the output, etc.
 	stdoutWriter := io.Discard
 	stderrWriter := io.Discard
-	logFileWriter := io.Discard
+	logFileStdoutWriter := io.Discard
+	logFileStderrWriter := io.Discard
 	var stdoutCollector strings.Builder
 	var stderrCollector strings.Builder
 
-	if !silent {
-		logFileWriter = logging.NewLogFileAppender(e.logFileName)
+	// stdout and stderr are appended to the log file line by line, so that their lines are not mixed. Depending on the
+	// configured log format, each line is written as a separate log entry.
+	var appenders []*logging.OutputAppender
 
-		if e.chatty || viper.GetBool("verbose") || loud {
-			stdoutWriter = os.Stdout
-			stderrWriter = os.Stderr
-		}
+	if !settings.silent && e.logFileName != "" {
+		logFileStdout := logging.NewLogFileAppender(e.logFileName, result.Command, "stdout")
+		logFileStderr := logging.NewLogFileAppender(e.logFileName, result.Command, "stderr")
+		appenders = append(appenders, logFileStdout, logFileStderr)
+		logFileStdoutWriter = logFileStdout
+		logFileStderrWriter = logFileStderr
 	}
 
-	writerStdout := io.MultiWriter(stdoutWriter, logFileWriter, &stdoutCollector)
-	writerStderr := io.MultiWriter(stderrWriter, logFileWriter, &stderrCollector)
+	if e.showsOutputOnConsole(settings) {
+		consoleStdout := logging.NewConsoleAppender(os.Stdout, result.Command, "stdout")
+		consoleStderr := logging.NewConsoleAppender(os.Stderr, result.Command, "stderr")
+		appenders = append(appenders, consoleStdout, consoleStderr)
+		stdoutWriter = consoleStdout
+		stderrWriter = consoleStderr
+	}
 
-	// 3. We connect the reader(s) to writer(s) via io.Copy, executed asynchronously. We wait until both are completed.
-	// Note: only after the io.Copy is done will our stdoutCollector be filled, so we have to wait!
-	var multiWritingSteps sync.WaitGroup
-	multiWritingSteps.Add(2)
+	if settings.taskOutput != nil {
+		stdoutWriter, stderrWriter, logFileStdoutWriter, logFileStderrWriter = settings.taskOutput.wrap(stdoutWriter,
+			stderrWriter, logFileStdoutWriter, logFileStderrWriter)
+	}
 
-	go func() {
-		io.Copy(writerStdout, cmdStdOut)
-		multiWritingSteps.Done()
-	}()
+	// secrets are masked in all sinks, except in the collectors, since the output is returned to the caller
+	redactingWriters := []*secrets.RedactingWriter{
+		secrets.NewRedactingWriter(stdoutWriter),
+		secrets.NewRedactingWriter(stderrWriter),
+		secrets.NewRedactingWriter(logFileStdoutWriter),
+		secrets.NewRedactingWriter(logFileStderrWriter),
+	}
 
-	go func() {
-		io.Copy(writerStderr, cmdStdErr)
-		multiWritingSteps.Done()
-	}()
+	writerStdout := io.MultiWriter(redactingWriters[0], redactingWriters[2], &stdoutCollector)
+	writerStderr := io.MultiWriter(redactingWriters[1], redactingWriters[3], &stderrCollector)
 
-	multiWritingSteps.Wait()
-	commandError := cmd.Wait()
+	// callbacks and abort patterns receive the output line by line, in addition to all other sinks
+	var lineWriters []*lineWriter
 
-	// some consoles always append a \n at the end, but this is safe to be removed
-	cleanedStringOutput := strings.TrimSuffix(stdoutCollector.String(), "\n")
+	if len(settings.stdoutCallbacks) > 0 || len(settings.abortPatterns) > 0 {
+		stdoutLines := newLineWriter(handleLine(settings.stdoutCallbacks, settings.abortPatterns, abort))
+		writerStdout = io.MultiWriter(writerStdout, stdoutLines)
+		lineWriters = append(lineWriters, stdoutLines)
+	}
+
+	if len(settings.stderrCallbacks) > 0 || len(settings.abortPatterns) > 0 {
+		stderrLines := newLineWriter(handleLine(settings.stderrCallbacks, settings.abortPatterns, abort))
+		writerStderr = io.MultiWriter(writerStderr, stderrLines)
+		lineWriters = append(lineWriters, stderrLines)
+	}
+
+	var commandError error
+
+	if cassetteMode == cassetteReplay {
+		// the recorded output goes through the same sinks, so that the console and log file look like in the recorded run
+		commandError = replayCommand(cassette, cassetteCommand, writerStdout, writerStderr, result)
+		if commandError != nil && result.ExitCode == -1 {
+			return result, commandError
+		}
+	} else {
+		// commands which can be cancelled are started in their own process group, so that the whole process tree can
+		// be killed. Otherwise, child processes would keep the output pipes open, and we would wait for them forever.
+		// The same applies while shutdown signals are handled, which are forwarded to the process group.
+		if ctx.Done() != nil || startsInOwnProcessGroup() {
+			prepareProcessTreeKill(cmd)
+		}
+
+		// 2. We "capture" the sources of command output from the command itself, by assigning the pipes to local
+		//    variables. These variables are of type io.Reader.
+		cmdStdOut, pipeError := cmd.StdoutPipe()
+		if pipeError != nil {
+			return result, pipeError
+		}
+
+		cmdStdErr, pipeError := cmd.StderrPipe()
+		if pipeError != nil {
+			return result, pipeError
+		}
+
+		// Start command
+		result.StartTime = time.Now()
+		commandStartError := cmd.Start()
+
+		if commandStartError != nil {
+			result.EndTime = time.Now()
+			result.Duration = result.EndTime.Sub(result.StartTime)
+
+			if cassetteMode == cassetteRecord {
+				recordCommand(cassette, cassetteCommand, "", commandStartError.Error(), result.ExitCode)
+			}
+
+			return result, commandStartError
+		}
+
+		stopWatching := watchContext(ctx, func() {
+			killProcessTree(cmd)
+		})
+		untrack := trackRunningCommand(cmd)
+
+		// 3. We connect the reader(s) to writer(s) via io.Copy, executed asynchronously. We wait until both are completed.
+		// Note: only after the io.Copy is done will our stdoutCollector be filled, so we have to wait!
+		var multiWritingSteps sync.WaitGroup
+		multiWritingSteps.Add(2)
+
+		go func() {
+			io.Copy(writerStdout, cmdStdOut)
+			multiWritingSteps.Done()
+		}()
+
+		go func() {
+			io.Copy(writerStderr, cmdStdErr)
+			multiWritingSteps.Done()
+		}()
+
+		multiWritingSteps.Wait()
+		commandError = cmd.Wait()
+		untrack()
+		stopWatching()
+
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	for _, writer := range redactingWriters {
+		writer.Flush()
+	}
+
+	for _, writer := range lineWriters {
+		writer.flush()
+	}
+
+	for _, appender := range appenders {
+		appender.Flush()
+	}
+
+	result.Stdout = stdoutCollector.String()
+	result.Stderr = stderrCollector.String()
+
+	if cassetteMode == cassetteRecord {
+		// also the output of silent commands is recorded, since it is usually parsed (like az account show). Secrets
+		// registered after the command returned are masked on the next write of the cassette.
+		recordCommand(cassette, cassetteCommand, result.Stdout, result.Stderr, result.ExitCode)
+	}
 
 	// composite error will be used to return stderr in case an error occurs, otherwise
 	// stderr will be ignored completely (unless verbose mode is used, or chatty executor)
 	var compositeError error
-
-	if commandError != nil {
+	var abortedError *AbortedError
+	var exitErr *exec.ExitError
+	var replayedExitErr *ExitError
+
+	if errors.As(context.Cause(ctx), &abortedError) {
+		abortedError.Command = result.Command
+		abortedError.Line = secrets.Redact(abortedError.Line)
+		abortedError.Stdout = secrets.Redact(result.Stdout)
+		abortedError.Stderr = secrets.Redact(result.Stderr)
+		compositeError = abortedError
+	} else if commandError != nil && ctx.Err() != nil {
+		compositeError = &TimeoutError{
+			Command: result.Command,
+			Stdout:  secrets.Redact(result.Stdout),
+			Stderr:  secrets.Redact(result.Stderr),
+			Cause:   ctx.Err(),
+		}
+	} else if errors.As(commandError, &exitErr) {
+		compositeError = &ExitError{
+			Command:  result.Command,
+			ExitCode: exitErr.ExitCode(),
+			Stdout:   secrets.Redact(result.Stdout),
+			Stderr:   secrets.Redact(result.Stderr),
+			Cause:    commandError,
+		}
+	} else if errors.As(commandError, &replayedExitErr) {
+		replayedExitErr.Stdout = secrets.Redact(result.Stdout)
+		replayedExitErr.Stderr = secrets.Redact(result.Stderr)
+		compositeError = replayedExitErr
+	} else if commandError != nil {
 		compositeError = fmt.Errorf("%w; "+
-			"Stderr stream: "+stderrCollector.String()+", "+
-			"Stdout stream: "+stdoutCollector.String(), commandError)
+			"Stderr stream: "+secrets.Redact(result.Stderr)+", "+
+			"Stdout stream: "+secrets.Redact(result.Stdout), commandError)
 	}
 
-	return cleanedStringOutput, internal.ReturnErrorOrPanic(compositeError)
+	if compositeError != nil && isShuttingDown() {
+		compositeError = fmt.Errorf("%w: %w", ErrInterrupted, compositeError)
+	}
+
+	return result, compositeError
 }
 
-func createAndStartSpinner() *spinner.Spinner {
-	spinner := spinner.New(spinner.CharSets[9], 100*time.Millisecond)
-	spinner.Prefix = "Please wait "
-	spinner.Color("green", "bold")
-	spinner.Start()
-	return spinner
+// replayCommand writes the recorded output of the command to the given writers, and returns the error matching the
+// recorded exit code. Result exit code stays -1 if the command is not found in the cassette.
+func replayCommand(cassette *cassette, command string, stdout io.Writer, stderr io.Writer, result *Result) error {
+	result.StartTime = time.Now()
+	defer func() {
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+	}()
+
+	entry, err := cassette.next(command)
+	if err != nil {
+		return err
+	}
+
+	io.WriteString(stdout, entry.Stdout)
+	io.WriteString(stderr, entry.Stderr)
+	result.ExitCode = entry.ExitCode
+
+	switch {
+	case entry.ExitCode == -1:
+		return fmt.Errorf("replayed command %s could not be started or was killed in the recorded run", command)
+	case entry.ExitCode != 0:
+		// same error as for executed commands, so that the exit code handling (e.g. terraform -detailed-exitcode) works
+		// the same, but without an *exec.ExitError (which cannot be created without running a process)
+		return &ExitError{
+			Command:  command,
+			ExitCode: entry.ExitCode,
+			Cause:    fmt.Errorf("exit status %d", entry.ExitCode),
+		}
+	}
+
+	return nil
 }
 
-func (e *executor) AskUserToConfirm(displayMessage string) bool {
-	// Asks the user for confirmation, returns true if the user inputs yes, otherwise false
-	logrus.Info(displayMessage + " [yes|no]")
+// recordCommand adds the command to the cassette. Since the cassette is only a diagnostic tool, a failure to write it
+// should not fail the command, therefore the error is only logged.
+func recordCommand(cassette *cassette, command string, stdout string, stderr string, exitCode int) {
+	err := cassette.record(cassetteEntry{
+		Command:  command,
+		Stdout:   secrets.Redact(stdout),
+		Stderr:   secrets.Redact(stderr),
+		ExitCode: exitCode,
+	})
+
+	if err != nil {
+		logrus.Warnf("could not record the command %s: %v", command, err)
+	}
+}
 
-	confirmation := bufio.NewScanner(e.stdin)
-	confirmation.Scan()
+// outputOf adapts the Result based methods to the plain output string returned by the Execute... methods
+func outputOf(result *Result, err error) (string, error) {
+	if result == nil {
+		return "", err
+	}
+
+	// some consoles always append a \n at the end, but this is safe to be removed
+	return strings.TrimSuffix(result.Stdout, "\n"), err
+}
+
+// withDefaultTimeout applies the executor default timeout, but only if the given context has no deadline of its own
+func (e *executor) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); e.defaultTimeout > 0 && !hasDeadline {
+		return context.WithTimeout(ctx, e.defaultTimeout)
+	}
+
+	return ctx, func() {}
+}
+
+// watchContext calls onDone if the context is done before the returned stop function is called
+func watchContext(ctx context.Context, onDone func()) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
 
-	acceptedValues := []string{"yes", "YES", "y", "Y"}
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
 
-	for okValueIndex := range acceptedValues {
-		okText := acceptedValues[okValueIndex]
-		text := confirmation.Text()
+	go func() {
+		defer close(finished)
 
-		if text == okText {
-			return true
+		select {
+		case <-ctx.Done():
+			onDone()
+		case <-stopped:
 		}
+	}()
+
+	return func() {
+		close(stopped)
+		<-finished
+	}
+}
+
+func (e *executor) AskUserToConfirm(displayMessage string) bool {
+	// Asks the user for confirmation, returns true if the user inputs yes, otherwise false
+	confirmed, err := e.confirm(displayMessage, "")
+	if err != nil {
+		logrus.Error(err)
 	}
 
-	return false
+	return confirmed
 }
 
 func (e *executor) AskUserToConfirmWithKeyword(displayMessage string, keyword string) bool {
-	logrus.Info(displayMessage)
+	confirmed, err := e.confirm(displayMessage, keyword)
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return confirmed
+}
 
-	confirmation := bufio.NewScanner(e.stdin)
-	confirmation.Scan()
+func (e *executor) AskUserToConfirmE(displayMessage string) (bool, error) {
+	confirmed, err := e.confirm(displayMessage, "")
+	return confirmed, e.returnErrorOrPanic(err)
+}
+
+func (e *executor) AskUserToConfirmWithKeywordE(displayMessage string, keyword string) (bool, error) {
+	confirmed, err := e.confirm(displayMessage, keyword)
+	return confirmed, e.returnErrorOrPanic(err)
+}
 
-	text := confirmation.Text()
+func (e *executor) ReadOnly() Executor {
+	readOnly := *e
+	readOnly.readOnly = true
 
-	if text == keyword {
-		return true
+	return &readOnly
+}
+
+func (e *executor) Try() Executor {
+	trying := *e
+	trying.trying = true
+
+	return &trying
+}
+
+func (e *executor) ErrorPolicy() error_handling.Policy {
+	if e.trying {
+		return error_handling.Return
 	}
 
-	return false
+	return e.errorPolicy
+}
+
+// returnErrorOrPanic raises the error according to the error policy of the executor. Errors of a Try view are expected
+// by the caller, and therefore returned as they are.
+func (e *executor) returnErrorOrPanic(err error) error {
+	if e.trying {
+		return err
+	}
+
+	return internal.ReturnErrorOrPanicWith(e.errorPolicy, err)
+}
+
+func (e *executor) RegisterSecret(value string) {
+	secrets.Register(value)
+}
+
+func (e *executor) SetEnv(env map[string]string) {
+	e.env.set(env)
 }
 
 func (e *executor) OverrideStdIn(override io.Reader) {
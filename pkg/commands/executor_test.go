@@ -1,18 +1,26 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -140,6 +148,114 @@ func (s *ExecutorTestSuite) Test_ErrorHasExitErrorWrapped() {
 	assert.Equal(s.T(), 5, exitErr.ExitCode())
 }
 
+func (s *ExecutorTestSuite) Test_FailedCommandReturnsExitErrorWithCode() {
+	var cmd string
+	if runtime.GOOS == "windows" {
+		cmd = "cmd /c \"echo failure 1>&2 && exit 4\""
+	} else {
+		cmd = "bash -c \"echo failure >&2; exit 4\""
+	}
+
+	_, err := s.exec.Execute(cmd)
+
+	var exitErr *ExitError
+	if s.ErrorAs(err, &exitErr) {
+		s.Equal(4, exitErr.ExitCode)
+		s.Contains(exitErr.Stderr, "failure")
+		s.Contains(exitErr.Command, "exit 4")
+	}
+
+	_, notFoundErr := s.exec.Execute("no-such-thing-to-do bla")
+	s.ErrorIs(notFoundErr, exec.ErrNotFound)
+	s.NotErrorAs(notFoundErr, &exitErr)
+}
+
+func (s *ExecutorTestSuite) Test_RunReturnsStructuredResult() {
+	var cmd string
+	if runtime.GOOS == "windows" {
+		cmd = "cmd /c \"echo out && echo err 1>&2 && exit 3\""
+	} else {
+		cmd = "bash -c \"echo out; echo err >&2; exit 3\""
+	}
+
+	result, err := s.exec.Run(context.Background(), cmd)
+
+	s.Error(err)
+	s.Equal(3, result.ExitCode)
+	s.Equal("out", strings.TrimSpace(result.Stdout))
+	s.Equal("err", strings.TrimSpace(result.Stderr))
+	s.Equal(cmd, result.Command)
+	s.False(result.StartTime.IsZero())
+	s.Equal(result.EndTime.Sub(result.StartTime), result.Duration)
+}
+
+func (s *ExecutorTestSuite) Test_RunReturnsResultForCommandsWhichCouldNotStart() {
+	result, err := s.exec.Run(context.Background(), "no-such-thing-to-do bla", WithSilentOutput())
+
+	s.Error(err)
+	s.Equal(-1, result.ExitCode)
+	s.Equal("", result.Stdout)
+}
+
+func (s *ExecutorTestSuite) Test_RegisteredSecretsAreMaskedInLogFileAndErrors() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("test relies on bash")
+	}
+
+	secret := "s3cr3t-" + uuid.New().String()
+	s.exec.RegisterSecret(secret)
+
+	out, err := s.exec.Execute("echo " + secret)
+	s.NoError(err)
+	s.Equal(secret, out) // returned output is not masked, since the caller might need the value
+
+	_, err = s.exec.Execute("bash -c \"echo " + secret + " >&2; exit 1\"")
+	s.Error(err)
+	s.NotContains(err.Error(), secret)
+	s.Contains(err.Error(), "***")
+
+	testing_utils.CheckFileContainsString(s.T(), testLogFileName, "echo ***")
+	testing_utils.CheckFileDoesNotContainString(s.T(), testLogFileName, secret)
+}
+
+func (s *ExecutorTestSuite) Test_CommandIsKilledWhenContextDeadlineExceeded() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("process tree test relies on bash")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// the background sleep keeps the output pipes open, so the whole process tree needs to be killed
+	start := time.Now()
+	out, err := s.exec.ExecuteContext(ctx, "bash -c \"echo partial output; sleep 30 & wait\"")
+
+	var timeoutErr *TimeoutError
+	s.True(errors.As(err, &timeoutErr))
+	s.True(errors.Is(err, context.DeadlineExceeded))
+	s.Less(time.Since(start), 10*time.Second)
+	s.Equal("partial output", out)
+	s.Contains(timeoutErr.Stdout, "partial output")
+
+	var exitErr *exec.ExitError
+	s.False(errors.As(err, &exitErr))
+
+	testing_utils.CheckFileContainsString(s.T(), testLogFileName, "partial output")
+}
+
+func (s *ExecutorTestSuite) Test_CancelledContextKillsTheCommand() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("test relies on sleep")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	_, err := s.exec.ExecuteSilentContext(ctx, "sleep 30")
+
+	s.True(errors.Is(err, context.Canceled))
+}
+
 func (s *ExecutorTestSuite) Test_Integration_ParsingComplexTypeFromCommandsIsPossible() {
 	// the two methods here can be further optimized in the future if we have more integrations tests, for example
 	// by having a list of conditions passed to a single CheckIntegrationTestPrerequisites method?
@@ -210,3 +326,410 @@ func Test_QuietExecutorWorksAsWell(t *testing.T) {
 		e.Execute("ls -la")
 	}
 }
+
+func Test_CustomExecutorWithoutOptionsIsQuiet(t *testing.T) {
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+
+	e := NewCustom(testLogFileName, logger, nil)
+
+	output, err := e.Execute("go version")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "go version")
+	assert.False(t, e.(*executor).chatty)
+	assert.Equal(t, error_handling.Default, e.ErrorPolicy())
+}
+
+func Test_DefaultTimeoutIsAppliedToCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sleep")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewCustom(testLogFileName, logger, &ExecutorOptions{DefaultTimeout: 200 * time.Millisecond})
+
+	_, err := e.Execute("sleep 30")
+
+	var timeoutErr *TimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	_, err = e.Execute("echo fast enough")
+	assert.NoError(t, err)
+}
+
+func Test_RecordedCommandsCanBeReplayedWithoutExecution(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	cassetteFileName := filepath.Join(t.TempDir(), "cassette.json")
+	markerFileName := filepath.Join(t.TempDir(), "marker")
+	e.RegisterSecret("recorded-secret")
+
+	// Act - record
+	viper.Set("record-commands", cassetteFileName)
+	recordedOutput, err := e.Execute("echo recorded-secret and more")
+	assert.NoError(t, err)
+	_, err = e.Execute("sh -c \"echo changes && exit 2\"")
+	assert.Error(t, err)
+	viper.Set("record-commands", "")
+
+	// Act - replay
+	viper.Set("replay-commands", cassetteFileName)
+	defer viper.Set("replay-commands", "")
+
+	replayedOutput, err := e.Execute("echo recorded-secret and more")
+	assert.NoError(t, err)
+	replayedResult, replayedErr := e.Run(context.Background(), "sh -c \"echo changes && exit 2\"")
+	_, notRecordedErr := e.Execute("touch " + markerFileName)
+
+	// Assert
+	assert.Equal(t, "recorded-secret and more", recordedOutput)
+	assert.Equal(t, "*** and more", replayedOutput)
+	testing_utils.CheckFileDoesNotContainString(t, cassetteFileName, "recorded-secret")
+
+	var exitErr *ExitError
+	assert.True(t, errors.As(replayedErr, &exitErr))
+	assert.Equal(t, 2, exitErr.ExitCode)
+	assert.Equal(t, "changes\n", exitErr.Stdout)
+	assert.Equal(t, "changes\n", replayedResult.Stdout)
+
+	var processExitErr *exec.ExitError
+	assert.False(t, errors.As(replayedErr, &processExitErr), "no process is started for replayed commands")
+
+	assert.Error(t, notRecordedErr)
+	assert.NoFileExists(t, markerFileName)
+}
+
+func Test_RecordedCassetteDoesNotLeakSecrets(t *testing.T) {
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	cassetteFileName := filepath.Join(t.TempDir(), "cassette.json")
+	viper.Set("record-commands", cassetteFileName)
+	defer viper.Set("record-commands", "")
+
+	// Act
+	// a key returned by a (silent) command is usually registered only after the command returned
+	key := uuid.New().String()
+	keyCommand := "echo " + key
+	if runtime.GOOS == "windows" {
+		keyCommand = "cmd /c echo " + key
+	}
+
+	keyOutput, err := e.ExecuteSilent(keyCommand)
+	assert.NoError(t, err)
+	e.RegisterSecret(keyOutput)
+
+	_, err = e.Execute("go env GOARCH")
+	assert.NoError(t, err)
+
+	// Assert
+	testing_utils.CheckFileDoesNotContainString(t, cassetteFileName, key)
+
+	content, err := os.ReadFile(cassetteFileName)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "***")
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(cassetteFileName)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func Test_ReplayedSilentCommandOutputCanBeParsed(t *testing.T) {
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	cassetteFileName := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Act - record
+	viper.Set("record-commands", cassetteFileName)
+	recordedOutput, err := e.ExecuteSilent("go env -json GOOS")
+	assert.NoError(t, err)
+	viper.Set("record-commands", "")
+
+	// Act - replay
+	viper.Set("replay-commands", cassetteFileName)
+	defer viper.Set("replay-commands", "")
+
+	replayedOutput, err := e.ExecuteSilent("go env -json GOOS")
+	assert.NoError(t, err)
+
+	// Assert
+	var replayedEnv map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(replayedOutput), &replayedEnv))
+	assert.Equal(t, runtime.GOOS, replayedEnv["GOOS"])
+	assert.Equal(t, recordedOutput, replayedOutput)
+}
+
+func Test_DryRunSkipsAllCommandsExceptReadOnlyOnes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on touch")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	skippedFileName := filepath.Join(t.TempDir(), "skipped")
+
+	viper.Set("dry-run", true)
+	defer viper.Set("dry-run", false)
+
+	// Act
+	skippedOutput, skippedErr := e.Execute("touch " + skippedFileName)
+	skippedTTYErr := e.ExecuteTTY("touch " + skippedFileName)
+	readOnlyOutput, readOnlyErr := e.ReadOnly().Execute("echo query")
+
+	// Assert
+	assert.NoError(t, skippedErr)
+	assert.NoError(t, skippedTTYErr)
+	assert.Equal(t, "", skippedOutput)
+	assert.NoFileExists(t, skippedFileName)
+
+	assert.NoError(t, readOnlyErr)
+	assert.Equal(t, "query", readOnlyOutput)
+	assert.True(t, IsDryRun())
+}
+
+func Test_StrictCommandParsingRejectsAmbiguousCommands(t *testing.T) {
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	strict := NewCustom(testLogFileName, logger, &ExecutorOptions{StrictCommandParsing: true})
+	lenient := NewQuiet(testLogFileName, logger)
+
+	_, strictErr := strict.Execute("echo object[\"property\"]")
+	_, unbalancedErr := lenient.Execute("echo \"unbalanced")
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(strictErr, &parseErr))
+	assert.True(t, errors.As(unbalancedErr, &parseErr))
+}
+
+func Test_EnvAndDirOptionsApplyOnlyToSingleInvocation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	t.Setenv("COPS_HQ_INHERITED", "inherited")
+	dir := t.TempDir()
+
+	// Act
+	withEnv, err := e.Run(context.Background(), "sh -c \"echo $COPS_HQ_TEST-$COPS_HQ_INHERITED\"",
+		WithEnv(map[string]string{"COPS_HQ_TEST": "first"}), WithEnv(map[string]string{"COPS_HQ_INHERITED": "overridden"}))
+	assert.NoError(t, err)
+
+	withoutInherited, err := e.Run(context.Background(), "/bin/sh -c \"echo $COPS_HQ_TEST-$COPS_HQ_INHERITED\"",
+		WithEnv(map[string]string{"COPS_HQ_TEST": "only"}), WithoutInheritedEnv())
+	assert.NoError(t, err)
+
+	withDir, err := e.Run(context.Background(), "pwd", WithDir(dir))
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "first-overridden\n", withEnv.Stdout)
+	assert.Equal(t, "only-\n", withoutInherited.Stdout)
+	assert.Equal(t, "", os.Getenv("COPS_HQ_TEST"))
+	assert.Equal(t, "inherited", os.Getenv("COPS_HQ_INHERITED"))
+
+	resolvedDir, _ := filepath.EvalSymlinks(dir)
+	assert.Equal(t, resolvedDir, strings.TrimSpace(withDir.Stdout))
+}
+
+func Test_ExecutorEnvAppliesToAllCommandsOfTheExecutorAndItsViews(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+	other := NewQuiet(testLogFileName, logger)
+
+	// Act
+	e.SetEnv(map[string]string{"COPS_HQ_TEST": "executor", "COPS_HQ_OVERRIDDEN": "executor"})
+
+	fromView, err := e.ReadOnly().Try().Execute("sh -c \"echo $COPS_HQ_TEST\"")
+	assert.NoError(t, err)
+
+	withOption, err := e.Run(context.Background(), "sh -c \"echo $COPS_HQ_TEST-$COPS_HQ_OVERRIDDEN\"",
+		WithEnv(map[string]string{"COPS_HQ_OVERRIDDEN": "option"}))
+	assert.NoError(t, err)
+
+	fromOtherExecutor, err := other.Execute("sh -c \"echo $COPS_HQ_TEST\"")
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "executor", fromView)
+	assert.Equal(t, "executor-option\n", withOption.Stdout)
+	assert.Equal(t, "", fromOtherExecutor)
+	assert.Equal(t, "", os.Getenv("COPS_HQ_TEST"))
+}
+
+func Test_LineCallbacksReceiveOutputWhileCommandIsRunning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	var stdoutLines []string
+	var stderrLines []string
+
+	// Act
+	result, err := e.Run(context.Background(), "sh -c \"echo first; echo error >&2; printf last\"",
+		WithStdoutCallback(func(line string) { stdoutLines = append(stdoutLines, line) }),
+		WithStderrCallback(func(line string) { stderrLines = append(stderrLines, line) }))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "last"}, stdoutLines)
+	assert.Equal(t, []string{"error"}, stderrLines)
+	assert.Equal(t, "first\nlast", result.Stdout)
+	testing_utils.CheckFileContainsString(t, testLogFileName, "first\n")
+}
+
+func Test_CommandIsAbortedWhenOutputMatchesPattern(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sleep")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	start := time.Now()
+
+	// Act
+	result, err := e.Run(context.Background(), "sh -c \"echo starting; echo 'Error: quota exceeded' >&2; sleep 30\"",
+		WithAbortOnPattern(regexp.MustCompile(`^Error: .*quota`)))
+
+	// Assert
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	var abortedErr *AbortedError
+	if assert.True(t, errors.As(err, &abortedErr)) {
+		assert.Equal(t, "Error: quota exceeded", abortedErr.Line)
+		assert.Equal(t, "starting\n", abortedErr.Stdout)
+	}
+
+	assert.Equal(t, -1, result.ExitCode)
+}
+
+func Test_InputIsPipedToStdinButNeverLogged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewChatty(testLogFileName, logger)
+
+	// Act
+	output, err := e.ExecuteSilentWithInput("sh -c \"wc -c\"", strings.NewReader("very-secret-input"))
+	assert.NoError(t, err)
+
+	echoed, err := e.ExecuteWithInput("cat", strings.NewReader("first\nsecond"))
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "17", strings.TrimSpace(output))
+	assert.Equal(t, "first\nsecond", echoed)
+	testing_utils.CheckFileDoesNotContainString(t, testLogFileName, "very-secret-input")
+}
+
+func Test_CommandOutputIsLoggedAsJsonEntriesTaggedWithCommand(t *testing.T) {
+	os.Remove(testLogFileName)
+	logger, err := logging.InitCustom(&logging.Options{LogFileName: testLogFileName, FileFormat: logging.FormatJSON})
+	assert.NoError(t, err)
+	defer os.Remove(testLogFileName)
+	defer logging.Init(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	// Act
+	_, err = e.Execute("go version")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, logging.Flush())
+
+	content, _ := os.ReadFile(testLogFileName)
+	var outputEntry map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var entry map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+
+		if entry[logging.FieldStream] == "stdout" {
+			outputEntry = entry
+		}
+	}
+
+	if assert.NotNil(t, outputEntry) {
+		assert.Contains(t, outputEntry[logging.FieldMessage], "go version go")
+		assert.Equal(t, "go version", outputEntry[logging.FieldCommand])
+		assert.Equal(t, logging.RunId(), outputEntry[logging.FieldRunId])
+	}
+}
+
+func Test_ErrorPolicyOverridesGlobalPanicMode(t *testing.T) {
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+
+	panicking := NewCustom(testLogFileName, logger, &ExecutorOptions{ErrorPolicy: error_handling.Panic})
+	returning := NewCustom(testLogFileName, logger, &ExecutorOptions{ErrorPolicy: error_handling.Return})
+
+	assert.Panics(t, func() {
+		panicking.Execute("no-such-thing-to-do bla")
+	})
+
+	error_handling.PanicOnAnyError = true
+	defer func() {
+		error_handling.PanicOnAnyError = false
+	}()
+
+	assert.NotPanics(t, func() {
+		_, err := returning.Execute("no-such-thing-to-do bla")
+		assert.Error(t, err)
+	})
+}
+
+func Test_TryReturnsErrorsOfPanickingExecutorConcurrently(t *testing.T) {
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewCustom(testLogFileName, logger, &ExecutorOptions{ErrorPolicy: error_handling.Panic})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := e.ReadOnly().Try().Execute("no-such-thing-to-do bla")
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, error_handling.Return, e.Try().ErrorPolicy())
+	assert.Equal(t, error_handling.Panic, e.ErrorPolicy())
+	assert.Panics(t, func() {
+		e.Execute("no-such-thing-to-do bla")
+	})
+}
@@ -1,10 +1,36 @@
 package commands
 
 import (
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/sirupsen/logrus"
 	"os"
+	"time"
 )
 
+// ExecutorOptions are used to create a custom Executor via NewCustom
+type ExecutorOptions struct {
+	// Chatty executor outputs the command output to both file and console at the same time. Otherwise, the
+	// output is only written to the file (see NewQuiet).
+	Chatty bool
+
+	// DefaultTimeout is applied to every executed command, unless the command is executed with a context which has
+	// its own deadline (e.g. via ExecuteContext). Commands running longer are killed, and a *TimeoutError is returned.
+	// Zero (default) means no timeout.
+	DefaultTimeout time.Duration
+
+	// StrictCommandParsing lets the executor reject ambiguous command strings (see TokenizeStrict), instead of
+	// executing them with a best guess. Commands with unbalanced quotes are always rejected.
+	StrictCommandParsing bool
+
+	// Middlewares wrap every command executed by the executor, in the given order (the first middleware is the
+	// outermost one). Check Middleware for details.
+	Middlewares []Middleware
+
+	// ErrorPolicy defines whether the errors of this executor (and of the recipes using it) are returned or raised as
+	// panic. Default follows the global error_handling.PanicOnAnyError flag. Check error_handling.Policy for details.
+	ErrorPolicy error_handling.Policy
+}
+
 // NewChatty creates a new Executor instance. Chatty executor outputs the command output to both file and console at
 // the same time. Best suited for application IaC projects.
 // Required dependencies are the log file name for command output, and the logging subsystem instance.
@@ -12,7 +38,7 @@ import (
 // interfering with formatting. Logging system is an explicit dependency, so that it is clear that the logging system
 // need to bo be initialized first, before creating an Executor.
 func NewChatty(logFileName string, logger *logrus.Logger) Executor {
-	return create(logFileName, logger, true)
+	return create(logFileName, logger, &ExecutorOptions{Chatty: true})
 }
 
 // NewQuiet creates a new Executor instance. Quiet executor outputs the command output only to a file, console output is
@@ -25,14 +51,35 @@ func NewChatty(logFileName string, logger *logrus.Logger) Executor {
 // interfering with formatting. Logging system is an explicit dependency, so that it is clear that the logging system
 // need to bo be initialized first, before creating an Executor.
 func NewQuiet(logFileName string, logger *logrus.Logger) Executor {
-	return create(logFileName, logger, false)
+	return create(logFileName, logger, &ExecutorOptions{Chatty: false})
 }
 
-func create(logFileName string, logger *logrus.Logger, chatty bool) Executor {
+// NewCustom creates a new Executor instance, with the behaviour configured via the given options. Check the
+// ExecutorOptions for details. Required dependencies are the same as for NewChatty and NewQuiet, except the log file
+// name can be empty, if logging to the file is disabled (see logging.Options). In this case, the command output is
+// only shown on the console (in chatty mode, or with the viper flag "verbose"). Nil options are the same as empty
+// options (a quiet executor).
+func NewCustom(logFileName string, logger *logrus.Logger, options *ExecutorOptions) Executor {
+	return create(logFileName, logger, options)
+}
+
+func create(logFileName string, logger *logrus.Logger, options *ExecutorOptions) Executor {
+	if options == nil {
+		options = &ExecutorOptions{}
+	}
+
 	e := &executor{
-		logFileName: logFileName,
-		logger:      logger,
-		chatty:      chatty,
+		logFileName:    logFileName,
+		logger:         logger,
+		chatty:         options.Chatty,
+		defaultTimeout: options.DefaultTimeout,
+
+		strictCommandParsing: options.StrictCommandParsing,
+		middlewares:          options.Middlewares,
+
+		errorPolicy: options.ErrorPolicy,
+
+		env: &executorEnv{},
 	}
 
 	e.stdin = os.Stdin
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// ciEnvironmentVariables are set by the common CI systems (generic, Azure DevOps and GitHub Actions)
+var ciEnvironmentVariables = []string{"CI", "TF_BUILD", "GITHUB_ACTIONS"}
+
+// IsCI returns true if one of the environment variables CI, TF_BUILD or GITHUB_ACTIONS is set to a true value
+func IsCI() bool {
+	for _, variable := range ciEnvironmentVariables {
+		if value, _ := strconv.ParseBool(os.Getenv(variable)); value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAutoConfirm returns true if all prompts should be answered automatically (viper flag "yes", set via the global cli
+// flag --yes). Confirmations are then approved, and input prompts return their default.
+func IsAutoConfirm() bool {
+	return viper.GetBool("yes")
+}
+
+func (e *executor) IsInteractive() bool {
+	if viper.GetBool("non-interactive") || IsAutoConfirm() {
+		return false
+	}
+
+	// input overridden via OverrideStdIn is answered by whoever provided it, independent of the environment
+	file, isFile := e.stdin.(*os.File)
+	if !isFile {
+		return e.stdin != nil
+	}
+
+	return !IsCI() && term.IsTerminal(int(file.Fd()))
+}
+
+// confirm is the non-panicking implementation of the confirmation prompts. Empty keyword accepts the usual yes values.
+func (e *executor) confirm(displayMessage string, keyword string) (bool, error) {
+	if keyword == "" {
+		displayMessage += " [yes|no]"
+	}
+
+	if IsAutoConfirm() {
+		logrus.Info(displayMessage + " (confirmed automatically via --yes)")
+		return true, nil
+	}
+
+	if !e.IsInteractive() {
+		return false, &NonInteractiveError{Prompt: displayMessage}
+	}
+
+	logrus.Info(displayMessage)
+
+	// a missing answer (closed stdin) is a "no"
+	text, _ := e.readLine()
+
+	if keyword != "" {
+		return text == keyword, nil
+	}
+
+	return slices.Contains([]string{"yes", "YES", "y", "Y"}, text), nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// lineWriter calls onLine for every complete line written (without the line break), and for the last incomplete line
+// on flush
+type lineWriter struct {
+	mutex   sync.Mutex
+	onLine  func(line string)
+	pending []byte
+}
+
+func newLineWriter(onLine func(line string)) *lineWriter {
+	return &lineWriter{onLine: onLine}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pending = append(w.pending, p...)
+
+	for {
+		lineEnd := bytes.IndexByte(w.pending, '\n')
+		if lineEnd < 0 {
+			break
+		}
+
+		w.onLine(strings.TrimSuffix(string(w.pending[:lineEnd]), "\r"))
+		w.pending = w.pending[lineEnd+1:]
+	}
+
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.pending) > 0 {
+		w.onLine(strings.TrimSuffix(string(w.pending), "\r"))
+		w.pending = nil
+	}
+}
+
+// handleLine returns a line handler, which calls all callbacks with the line, and aborts the command if the line matches
+// one of the abort patterns
+func handleLine(callbacks []func(line string), abortPatterns []*regexp.Regexp, abort context.CancelCauseFunc) func(line string) {
+	return func(line string) {
+		for _, callback := range callbacks {
+			callback(line)
+		}
+
+		for _, pattern := range abortPatterns {
+			if pattern.MatchString(line) {
+				// only the first abort cause is kept by the context
+				abort(&AbortedError{Pattern: pattern.String(), Line: line})
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+)
+
+// Middleware wraps every command executed by an Executor with cross-cutting behaviour, like timing metrics, auditing
+// or injecting additional arguments. Middlewares are registered via ExecutorOptions (or HqOptions), and therefore apply
+// to all commands, including the ones executed by the recipes (terraform, helm, sops, copsctl, azure login...).
+// Commands skipped in dry-run mode are not passed to the middlewares. Since commands can be executed in parallel (see
+// Executor.ExecuteParallel), middlewares need to be safe for concurrent use.
+type Middleware interface {
+	// Before is called before the command is started. The command can be modified (e.g. by appending arguments to
+	// cmd.Args or variables to cmd.Env), changes are reflected in the logged command line. The returned context is used
+	// for the execution and passed to After, so it can carry values from Before to After (like the start time). If an
+	// error is returned, the command is not executed, and the error is returned to the caller. After is then only called
+	// for the middlewares registered before this one.
+	Before(ctx context.Context, cmd *exec.Cmd) (context.Context, error)
+
+	// After is called once the command finished, also if the execution failed. The result and the error returned by
+	// After are passed to the next middleware, and finally returned to the caller, so they can be replaced (e.g. to
+	// ignore a known error). Result is never nil. Same as with Executor.Run, Stdout and Stderr are not masked.
+	After(ctx context.Context, cmd *exec.Cmd, result *Result, err error) (*Result, error)
+}
+
+// MiddlewareFuncs is a Middleware built from plain functions, for middlewares which only need one of the hooks.
+// Hooks which are nil are skipped.
+type MiddlewareFuncs struct {
+	BeforeFunc func(ctx context.Context, cmd *exec.Cmd) (context.Context, error)
+	AfterFunc  func(ctx context.Context, cmd *exec.Cmd, result *Result, err error) (*Result, error)
+}
+
+func (m MiddlewareFuncs) Before(ctx context.Context, cmd *exec.Cmd) (context.Context, error) {
+	if m.BeforeFunc == nil {
+		return ctx, nil
+	}
+
+	return m.BeforeFunc(ctx, cmd)
+}
+
+func (m MiddlewareFuncs) After(ctx context.Context, cmd *exec.Cmd, result *Result, err error) (*Result, error) {
+	if m.AfterFunc == nil {
+		return result, err
+	}
+
+	return m.AfterFunc(ctx, cmd, result, err)
+}
+
+// withMiddlewares calls the Before hooks in the order of registration, then the execute function, and then the After
+// hooks in the reverse order, so that the first registered middleware is the outermost one. The execute function gets
+// the command line to show in the logs, updated in case a middleware changed the command arguments.
+func (e *executor) withMiddlewares(ctx context.Context, command string, cmd *exec.Cmd, execute func(ctx context.Context, command string) (*Result, error)) (*Result, error) {
+	if len(e.middlewares) == 0 {
+		return execute(ctx, command)
+	}
+
+	originalArgs := slices.Clone(cmd.Args)
+
+	var result *Result
+	var err error
+	called := 0
+
+	for _, middleware := range e.middlewares {
+		var middlewareCtx context.Context
+
+		middlewareCtx, err = middleware.Before(ctx, cmd)
+		if err != nil {
+			result = &Result{Command: secrets.Redact(command), ExitCode: -1}
+			break
+		}
+
+		if middlewareCtx != nil {
+			ctx = middlewareCtx
+		}
+
+		called++
+	}
+
+	if called == len(e.middlewares) {
+		if !slices.Equal(originalArgs, cmd.Args) {
+			command = strings.Join(cmd.Args, " ")
+		}
+
+		result, err = execute(ctx, command)
+	}
+
+	for i := called - 1; i >= 0; i-- {
+		result, err = e.middlewares[i].After(ctx, cmd, result, err)
+
+		if result == nil {
+			result = &Result{Command: secrets.Redact(command), ExitCode: -1}
+		}
+	}
+
+	return result, err
+}
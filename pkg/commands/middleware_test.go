@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMiddleware struct {
+	name  string
+	calls *[]string
+}
+
+func (m *recordingMiddleware) Before(ctx context.Context, cmd *exec.Cmd) (context.Context, error) {
+	*m.calls = append(*m.calls, "before "+m.name)
+	return ctx, nil
+}
+
+func (m *recordingMiddleware) After(ctx context.Context, cmd *exec.Cmd, result *Result, err error) (*Result, error) {
+	*m.calls = append(*m.calls, "after "+m.name)
+	return result, err
+}
+
+func Test_MiddlewaresWrapCommandsInRegistrationOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on echo")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+
+	var calls []string
+	injectArgument := MiddlewareFuncs{
+		BeforeFunc: func(ctx context.Context, cmd *exec.Cmd) (context.Context, error) {
+			cmd.Args = append(cmd.Args, "--only-show-errors")
+			return ctx, nil
+		},
+	}
+
+	e := NewCustom(testLogFileName, logger, &ExecutorOptions{
+		Middlewares: []Middleware{
+			&recordingMiddleware{name: "outer", calls: &calls},
+			&recordingMiddleware{name: "inner", calls: &calls},
+			injectArgument,
+		},
+	})
+
+	// Act
+	output, err := e.Execute("echo hello")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "hello --only-show-errors", output)
+	assert.Equal(t, []string{"before outer", "before inner", "after inner", "after outer"}, calls)
+	testing_utils.CheckFileContainsString(t, testLogFileName, "[Command] echo hello --only-show-errors")
+}
+
+func Test_MiddlewareCanPreventExecutionAndReplaceErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+
+	var calls []string
+	rateLimitErr := errors.New("rate limit reached")
+
+	e := NewCustom(testLogFileName, logger, &ExecutorOptions{
+		Middlewares: []Middleware{
+			&recordingMiddleware{name: "outer", calls: &calls},
+			MiddlewareFuncs{
+				BeforeFunc: func(ctx context.Context, cmd *exec.Cmd) (context.Context, error) {
+					if cmd.Args[0] == "az" {
+						return nil, rateLimitErr
+					}
+					return ctx, nil
+				},
+				AfterFunc: func(ctx context.Context, cmd *exec.Cmd, result *Result, err error) (*Result, error) {
+					if result.ExitCode == 3 {
+						return result, nil
+					}
+					return result, err
+				},
+			},
+			&recordingMiddleware{name: "inner", calls: &calls},
+		},
+	})
+
+	// Act
+	_, prevented := e.Run(context.Background(), "az account show")
+	ignored, ignoredErr := e.Run(context.Background(), "sh -c \"exit 3\"")
+
+	// Assert
+	assert.ErrorIs(t, prevented, rateLimitErr)
+	assert.NoError(t, ignoredErr)
+	assert.Equal(t, 3, ignored.ExitCode)
+	assert.Equal(t, []string{
+		"before outer", "after outer",
+		"before outer", "before inner", "after inner", "after outer",
+	}, calls)
+	testing_utils.CheckFileDoesNotContainString(t, testLogFileName, "az account show")
+}
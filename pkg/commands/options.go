@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ExecuteOption changes the behaviour of a single command execution via Executor.Run or Executor.RunCmd
+type ExecuteOption func(settings *executeSettings)
+
+type executeSettings struct {
+	silent       bool
+	loud         bool
+	progressInfo bool
+	progressStep string
+
+	env                 map[string]string
+	withoutInheritedEnv bool
+	dir                 string
+	stdin               io.Reader
+	retryPolicy         *RetryPolicy
+
+	stdoutCallbacks []func(line string)
+	stderrCallbacks []func(line string)
+	abortPatterns   []*regexp.Regexp
+
+	// only set for tasks of Executor.ExecuteParallel
+	taskName   string
+	taskOutput *taskOutput
+}
+
+// WithSilentOutput suppresses the command output from both the console and the log file (see Executor.ExecuteSilent)
+func WithSilentOutput() ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.silent = true
+	}
+}
+
+// WithLoudOutput always shows the command output on the console, irrelevant of the chatty / quiet setting
+// (see Executor.ExecuteLoud)
+func WithLoudOutput() ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.loud = true
+	}
+}
+
+// WithProgressInfo shows an infinite progress bar while the command is running (see Executor.ExecuteWithProgressInfo).
+// The progress bar shows the program and subcommands of the command (e.g. "helm upgrade"), use WithProgressStep for a
+// more descriptive step name.
+func WithProgressInfo() ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.progressInfo = true
+	}
+}
+
+// WithProgressStep is same as WithProgressInfo, except the progress bar shows the given step description (e.g.
+// "Waiting for the helm release my-app"), which is also used for the completion line in the log file and the
+// "still running" heartbeat log lines (see Executor.ExecuteWithProgressStep)
+func WithProgressStep(step string) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.progressInfo = true
+		settings.progressStep = step
+	}
+}
+
+// WithEnv sets additional environment variables for the command, without changing the environment of the current process.
+// Given variables override inherited variables with the same name. Can be given multiple times. Values are never logged,
+// so it is safe to pass secrets this way (which also keeps them away from all other started processes).
+func WithEnv(env map[string]string) ExecuteOption {
+	return func(settings *executeSettings) {
+		if settings.env == nil {
+			settings.env = make(map[string]string)
+		}
+
+		for name, value := range env {
+			settings.env[name] = value
+		}
+	}
+}
+
+// WithoutInheritedEnv starts the command with only the environment variables given via WithEnv (or set on the
+// *exec.Cmd given to RunCmd), instead of inheriting the environment of the current process
+func WithoutInheritedEnv() ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.withoutInheritedEnv = true
+	}
+}
+
+// WithDir sets the working directory of the command. Relative paths are resolved from the working directory of the
+// current process.
+func WithDir(path string) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.dir = path
+	}
+}
+
+// WithStdoutCallback calls the callback for every stdout line of the command (without the line break), as soon as the
+// line is available. Same as the output returned to the caller, the lines are not masked. The console, log file and
+// collected output are not affected. Callbacks are called from the goroutine reading the output, so they should return
+// quickly, and might be called concurrently with stderr callbacks. Can be given multiple times.
+func WithStdoutCallback(callback func(line string)) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.stdoutCallbacks = append(settings.stdoutCallbacks, callback)
+	}
+}
+
+// WithStderrCallback is same as WithStdoutCallback, but for the stderr lines of the command
+func WithStderrCallback(callback func(line string)) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.stderrCallbacks = append(settings.stderrCallbacks, callback)
+	}
+}
+
+// WithAbortOnPattern kills the command (including its child processes) as soon as a stdout or stderr line matches the
+// pattern, and returns an *AbortedError. Useful to fail early on known fatal messages, instead of waiting for a long
+// running command to finish or time out. Can be given multiple times.
+func WithAbortOnPattern(pattern *regexp.Regexp) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.abortPatterns = append(settings.abortPatterns, pattern)
+	}
+}
+
+// WithStdin pipes the given input into the stdin of the command. The input is never logged (nor recorded in cassette
+// files), so it is safe to pass secrets this way, instead of writing them to temporary files.
+func WithStdin(input io.Reader) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.stdin = input
+	}
+}
+
+// WithRetry retries the command according to the given policy, if it fails. Every retry is logged as a warning.
+// Check RetryPolicy for details.
+func WithRetry(policy RetryPolicy) ExecuteOption {
+	return func(settings *executeSettings) {
+		settings.retryPolicy = &policy
+	}
+}
+
+// applyTo sets the working directory, the environment and the stdin of the command, if configured
+func (settings *executeSettings) applyTo(cmd *exec.Cmd) {
+	if settings.dir != "" {
+		cmd.Dir = settings.dir
+	}
+
+	if settings.stdin != nil {
+		cmd.Stdin = settings.stdin
+	}
+
+	if len(settings.env) == 0 && !settings.withoutInheritedEnv {
+		return
+	}
+
+	env := append([]string{}, cmd.Env...)
+	if cmd.Env == nil && !settings.withoutInheritedEnv {
+		env = os.Environ()
+	}
+
+	names := make([]string, 0, len(settings.env))
+	for name := range settings.env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// os/exec uses the last value in case of duplicate names, so appending is enough to override inherited values
+	for _, name := range names {
+		env = append(env, name+"="+settings.env[name])
+	}
+
+	cmd.Env = env
+}
+
+// ExecuteSettings are the settings of a single execution, resulting from the given ExecuteOption values (see
+// ResolveExecuteOptions)
+type ExecuteSettings struct {
+	Silent       bool
+	Loud         bool
+	ProgressInfo bool
+	ProgressStep string
+
+	Env                 map[string]string
+	WithoutInheritedEnv bool
+	Dir                 string
+	Stdin               io.Reader
+	RetryPolicy         *RetryPolicy
+
+	StdoutCallbacks []func(line string)
+	StderrCallbacks []func(line string)
+	AbortPatterns   []*regexp.Regexp
+}
+
+// ResolveExecuteOptions returns the settings resulting from the given options. Useful for Executor implementations
+// outside of this package, like commandstest.FakeExecutor.
+func ResolveExecuteOptions(options ...ExecuteOption) ExecuteSettings {
+	settings := newExecuteSettings(options)
+
+	return ExecuteSettings{
+		Silent:       settings.silent,
+		Loud:         settings.loud,
+		ProgressInfo: settings.progressInfo,
+		ProgressStep: settings.progressStep,
+
+		Env:                 settings.env,
+		WithoutInheritedEnv: settings.withoutInheritedEnv,
+		Dir:                 settings.dir,
+		Stdin:               settings.stdin,
+		RetryPolicy:         settings.retryPolicy,
+
+		StdoutCallbacks: settings.stdoutCallbacks,
+		StderrCallbacks: settings.stderrCallbacks,
+		AbortPatterns:   settings.abortPatterns,
+	}
+}
+
+// executorEnv holds the environment variables set via Executor.SetEnv
+type executorEnv struct {
+	mutex  sync.Mutex
+	values map[string]string
+}
+
+func (env *executorEnv) set(values map[string]string) {
+	env.mutex.Lock()
+	defer env.mutex.Unlock()
+
+	if env.values == nil {
+		env.values = make(map[string]string)
+	}
+
+	for name, value := range values {
+		env.values[name] = value
+	}
+}
+
+// addTo adds the variables to the settings of a single execution, without overriding the ones given via WithEnv
+func (env *executorEnv) addTo(settings *executeSettings) {
+	env.mutex.Lock()
+	defer env.mutex.Unlock()
+
+	for name, value := range env.values {
+		if settings.env == nil {
+			settings.env = make(map[string]string)
+		}
+
+		if _, set := settings.env[name]; !set {
+			settings.env[name] = value
+		}
+	}
+}
+
+func newExecuteSettings(options []ExecuteOption) *executeSettings {
+	settings := &executeSettings{}
+
+	for _, option := range options {
+		option(settings)
+	}
+
+	return settings
+}
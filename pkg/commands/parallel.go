@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ParallelTask is a single command executed via Executor.ExecuteParallel
+type ParallelTask struct {
+	// Name is used to label the output of the task. Defaults to the (1-based) position of the task.
+	Name string
+
+	// Command is the command to execute, same as for Executor.Run
+	Command string
+
+	// Cmd can be used instead of Command to provide the os/exec command directly, same as for Executor.RunCmd
+	Cmd *exec.Cmd
+
+	// Options change the behaviour of this task, same as for Executor.Run. WithProgressInfo is ignored, since multiple
+	// progress indicators at the same time would garble the console.
+	Options []ExecuteOption
+}
+
+// ParallelSettings configure the execution of all tasks via Executor.ExecuteParallel
+type ParallelSettings struct {
+	// MaxConcurrency limits the number of tasks running at the same time. Zero (default) means no limit.
+	MaxConcurrency int
+
+	// FailFast stops starting new tasks once a task failed. Already running tasks are not stopped (use a cancellable
+	// context for that).
+	FailFast bool
+
+	// StreamOutput writes the output lines as soon as they are available, prefixed with the task name. Otherwise, the
+	// output of each task is buffered, and written as one labelled block once the task finished.
+	StreamOutput bool
+}
+
+func (e *executor) ExecuteParallel(ctx context.Context, tasks []ParallelTask, settings ParallelSettings) ([]*Result, error) {
+	results := make([]*Result, len(tasks))
+	taskErrors := make([]error, len(tasks))
+
+	concurrency := settings.MaxConcurrency
+	if concurrency <= 0 || concurrency > len(tasks) {
+		concurrency = len(tasks)
+	}
+
+	// shared by all tasks, so that the output blocks (or lines) are written one after another
+	var outputLock sync.Mutex
+	var failed atomic.Bool
+	var running sync.WaitGroup
+	slots := make(chan struct{}, max(concurrency, 1))
+
+	for i, task := range tasks {
+		slots <- struct{}{}
+
+		if settings.FailFast && failed.Load() {
+			break
+		}
+
+		running.Add(1)
+
+		go func() {
+			defer running.Done()
+			defer func() { <-slots }()
+
+			results[i], taskErrors[i] = e.runParallelTask(ctx, taskName(i, task), task, settings.StreamOutput, &outputLock)
+
+			if taskErrors[i] != nil {
+				failed.Store(true)
+			}
+		}()
+	}
+
+	running.Wait()
+
+	var aggregatedErrors []error
+	for i, err := range taskErrors {
+		if err != nil {
+			aggregatedErrors = append(aggregatedErrors, fmt.Errorf("task %s failed: %w", taskName(i, tasks[i]), err))
+		}
+	}
+
+	// tasks run in separate goroutines, therefore only the aggregated error can panic (on the calling goroutine)
+	return results, e.returnErrorOrPanic(errors.Join(aggregatedErrors...))
+}
+
+func (e *executor) runParallelTask(ctx context.Context, name string, task ParallelTask, stream bool, outputLock *sync.Mutex) (*Result, error) {
+	settings := newExecuteSettings(task.Options)
+	settings.progressInfo = false
+	settings.taskName = name
+	settings.taskOutput = &taskOutput{name: name, stream: stream, lock: outputLock}
+
+	defer settings.taskOutput.finish()
+
+	return e.run(ctx, task.Command, task.Cmd, settings)
+}
+
+func taskName(index int, task ParallelTask) string {
+	if task.Name != "" {
+		return task.Name
+	}
+
+	return strconv.Itoa(index + 1)
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParallelTasksOutputIsWrittenAsBlocks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	// Act - output lines of both tasks would be interleaved without buffering
+	results, err := e.ExecuteParallel(context.Background(), []ParallelTask{
+		{Name: "first", Command: "sh -c \"echo a1; sleep 0.2; echo a2\""},
+		{Name: "second", Command: "sh -c \"sleep 0.1; echo b1; sleep 0.2; echo b2\""},
+	}, ParallelSettings{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "a1\na2\n", results[0].Stdout)
+	assert.Equal(t, "b1\nb2\n", results[1].Stdout)
+	testing_utils.CheckFileContainsString(t, testLogFileName, "----- [first] -----\na1\na2\n")
+	testing_utils.CheckFileContainsString(t, testLogFileName, "----- [second] -----\nb1\nb2\n")
+}
+
+func Test_ParallelTasksOutputCanBeStreamedWithPrefixes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	// Act
+	_, err := e.ExecuteParallel(context.Background(), []ParallelTask{
+		{Name: "first", Command: "sh -c \"echo a1; sleep 0.2; printf a2\""},
+		{Name: "second", Cmd: exec.Command("sh", "-c", "sleep 0.1; echo b1")},
+	}, ParallelSettings{StreamOutput: true})
+
+	// Assert
+	assert.NoError(t, err)
+	testing_utils.CheckFileContainsString(t, testLogFileName, "[first] a1\n")
+	testing_utils.CheckFileContainsString(t, testLogFileName, "[first] a2\n")
+	testing_utils.CheckFileContainsString(t, testLogFileName, "[second] b1\n")
+}
+
+func Test_ParallelTaskErrorsAreAggregatedAndFailFastStopsStartingTasks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	tasks := []ParallelTask{
+		{Name: "failing", Command: "sh -c \"exit 3\""},
+		{Name: "also-failing", Command: "sh -c \"exit 4\""},
+	}
+
+	// Act
+	allResults, allErr := e.ExecuteParallel(context.Background(), tasks, ParallelSettings{})
+	failFastResults, failFastErr := e.ExecuteParallel(context.Background(), tasks,
+		ParallelSettings{MaxConcurrency: 1, FailFast: true})
+
+	// Assert
+	assert.ErrorContains(t, allErr, "task failing failed")
+	assert.ErrorContains(t, allErr, "task also-failing failed")
+	assert.Equal(t, 4, allResults[1].ExitCode)
+
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(failFastErr, &exitErr))
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.NotContains(t, failFastErr.Error(), "also-failing")
+	assert.Nil(t, failFastResults[1])
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// prepareProcessTreeKill starts the command in its own process group, so that killProcessTree can reach all
+// processes started by the command as well
+func prepareProcessTreeKill(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	// negative pid addresses the whole process group created via prepareProcessTreeKill
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// signalProcessTree sends the signal to the process group of the command, if it was started via prepareProcessTreeKill.
+// Commands sharing the process group of the program (TTY commands) get the signal directly, except Ctrl+C (SIGINT),
+// which the terminal already sent to the whole group.
+func signalProcessTree(cmd *exec.Cmd, signal os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		if signal == os.Interrupt {
+			return nil
+		}
+
+		return cmd.Process.Signal(signal)
+	}
+
+	return syscall.Kill(-cmd.Process.Pid, signal.(syscall.Signal))
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// prepareProcessTreeKill is a no-op on Windows, since taskkill is able to find the child processes on its own
+func prepareProcessTreeKill(cmd *exec.Cmd) {
+}
+
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+// signalProcessTree is a no-op on Windows, since signals cannot be sent to other processes. Ctrl+C reaches all processes
+// attached to the console anyway, commands still running after the grace period are killed.
+func signalProcessTree(cmd *exec.Cmd, signal os.Signal) error {
+	return nil
+}
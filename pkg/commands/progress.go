@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/conplementag/cops-hq/v2/internal/logging"
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/spf13/viper"
+)
+
+// progressHeartbeatInterval is the interval of the "still running" log lines, which replace the progress indicator if
+// the viper flag "silence-long-running-progress-indicators" is set (e.g. in CI, where build agents might kill jobs
+// without any output for a longer time)
+var progressHeartbeatInterval = time.Minute
+
+type progressIndicator struct {
+	step        string
+	startTime   time.Time
+	logFileName string
+
+	spinner *spinner.Spinner
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// startProgress shows the progress of the given step, either as a spinner with the elapsed time, or as periodic
+// heartbeat log lines if the progress indicators are silenced. The returned indicator has to be finished.
+func (e *executor) startProgress(step string) *progressIndicator {
+	progress := &progressIndicator{
+		step:        step,
+		startTime:   time.Now(),
+		logFileName: e.logFileName,
+	}
+
+	if viper.GetBool("silence-long-running-progress-indicators") {
+		progress.stop = make(chan struct{})
+		progress.stopped = make(chan struct{})
+
+		go func() {
+			defer close(progress.stopped)
+
+			ticker := time.NewTicker(progressHeartbeatInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					e.logger.Infof("Still running %s (%s elapsed)", step, formatElapsed(progress.elapsed()))
+				case <-progress.stop:
+					return
+				}
+			}
+		}()
+	} else {
+		progress.spinner = spinner.New(spinner.CharSets[9], 100*time.Millisecond)
+		progress.spinner.Prefix = step + " "
+		progress.spinner.PreUpdate = func(s *spinner.Spinner) {
+			s.Suffix = " " + formatElapsed(progress.elapsed())
+		}
+		progress.spinner.Color("green", "bold")
+		progress.spinner.Start()
+	}
+
+	return progress
+}
+
+func (progress *progressIndicator) elapsed() time.Duration {
+	return time.Since(progress.startTime)
+}
+
+// finish stops the progress indicator, and writes a completion line with the duration of the step to the log file (if
+// file logging is enabled)
+func (progress *progressIndicator) finish(err error) {
+	if progress.spinner != nil {
+		progress.spinner.Stop()
+	} else {
+		close(progress.stop)
+		<-progress.stopped
+	}
+
+	outcome := "finished"
+	if err != nil {
+		outcome = "failed"
+	}
+
+	if progress.logFileName == "" {
+		return
+	}
+
+	message := fmt.Sprintf("[Progress] %s %s after %s", progress.step, outcome, formatElapsed(progress.elapsed()))
+	logging.SharedLogFile(progress.logFileName).WriteLine(message, "")
+}
+
+// progressStepOf returns the step shown by the progress indicator, which defaults to the program and the subcommands of
+// the command (e.g. "helm upgrade" or "az storage account create")
+func (settings *executeSettings) progressStepOf(args []string) string {
+	if settings.progressStep != "" {
+		return settings.progressStep
+	}
+
+	if len(args) == 0 {
+		return "Please wait"
+	}
+
+	words := []string{filepath.Base(args[0])}
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") || len(words) == 4 {
+			break
+		}
+
+		words = append(words, arg)
+	}
+
+	return secrets.Redact(strings.Join(words, " "))
+}
+
+// formatElapsed formats the duration in whole seconds, omitting zero seconds for full minutes (e.g. "42s", "5m" or
+// "5m12s")
+func formatElapsed(duration time.Duration) string {
+	duration = duration.Round(time.Second)
+
+	if duration >= time.Minute && duration%time.Minute == 0 {
+		return strings.TrimSuffix(duration.String(), "0s")
+	}
+
+	return duration.String()
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SilencedProgressLogsHeartbeatsAndCompletionLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sleep")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	viper.Set("silence-long-running-progress-indicators", true)
+	defer viper.Set("silence-long-running-progress-indicators", false)
+
+	defaultInterval := progressHeartbeatInterval
+	progressHeartbeatInterval = 100 * time.Millisecond
+	defer func() { progressHeartbeatInterval = defaultInterval }()
+
+	// Act
+	_, err := e.Run(context.Background(), "sleep 1", WithProgressStep("Creating the storage account"))
+	_, failedErr := e.Run(context.Background(), "sh -c \"exit 1\"", WithProgressInfo())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Error(t, failedErr)
+	testing_utils.CheckFileContainsString(t, testLogFileName, "Still running Creating the storage account (")
+	testing_utils.CheckFileContainsString(t, testLogFileName, "[Progress] Creating the storage account finished after 1s")
+	testing_utils.CheckFileContainsString(t, testLogFileName, "[Progress] sh failed after 0s")
+}
+
+func Test_ProgressStepDefaultsToProgramAndSubcommands(t *testing.T) {
+	settings := newExecuteSettings(nil)
+
+	assert.Equal(t, "helm upgrade", settings.progressStepOf([]string{"/usr/bin/helm", "upgrade", "--install", "app"}))
+	assert.Equal(t, "az storage account create", settings.progressStepOf([]string{"az", "storage", "account", "create", "extra"}))
+	assert.Equal(t, "Please wait", settings.progressStepOf(nil))
+
+	WithProgressStep("Waiting for the cluster")(settings)
+	assert.Equal(t, "Waiting for the cluster", settings.progressStepOf([]string{"az", "aks", "create"}))
+}
+
+func Test_ElapsedTimeIsFormattedInWholeSeconds(t *testing.T) {
+	assert.Equal(t, "42s", formatElapsed(42*time.Second+300*time.Millisecond))
+	assert.Equal(t, "5m", formatElapsed(5*time.Minute+200*time.Millisecond))
+	assert.Equal(t, "5m12s", formatElapsed(5*time.Minute+12*time.Second))
+	assert.Equal(t, "1h0m", formatElapsed(time.Hour))
+}
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// PromptOptions are used to configure the user prompts (AskUserForInput, AskUserForSecret, AskUserToSelect and
+// AskUserToMultiSelect). Nil options can be passed if no configuration is needed.
+type PromptOptions struct {
+	// Default is used if the user confirms the prompt without any input, and when running non-interactively. For
+	// AskUserToSelect, it has to be one of the choices.
+	Default string
+
+	// DefaultSelection is same as Default, but for AskUserToMultiSelect. Values have to be part of the choices.
+	DefaultSelection []string
+
+	// Validate is called with the user input of AskUserForInput and AskUserForSecret. If an error is returned, it is shown
+	// to the user, and the user is asked again.
+	Validate func(input string) error
+}
+
+func (e *executor) AskUserForInput(displayMessage string, options *PromptOptions) (string, error) {
+	return e.askUserForText(displayMessage, options, false)
+}
+
+func (e *executor) AskUserForSecret(displayMessage string, options *PromptOptions) (string, error) {
+	return e.askUserForText(displayMessage, options, true)
+}
+
+func (e *executor) AskUserToSelect(displayMessage string, choices []string, options *PromptOptions) (string, error) {
+	options = defaultPromptOptions(options)
+
+	if len(choices) == 0 {
+		return "", e.returnErrorOrPanic(errors.New("no choices given for the prompt: " + displayMessage))
+	}
+
+	if options.Default != "" && !slices.Contains(choices, options.Default) {
+		return "", e.returnErrorOrPanic(fmt.Errorf("default %s is not one of the choices", options.Default))
+	}
+
+	if !e.IsInteractive() {
+		return e.nonInteractiveAnswer(displayMessage, options.Default)
+	}
+
+	for {
+		logrus.Info(displayMessage + defaultHint(options.Default))
+		logChoices(choices)
+
+		input, err := e.readLine()
+		if err != nil {
+			return "", e.returnErrorOrPanic(err)
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" && options.Default != "" {
+			return options.Default, nil
+		}
+
+		choice, err := parseChoice(input, choices)
+		if err == nil {
+			return choice, nil
+		}
+
+		logrus.Warn(err)
+	}
+}
+
+func (e *executor) AskUserToMultiSelect(displayMessage string, choices []string, options *PromptOptions) ([]string, error) {
+	options = defaultPromptOptions(options)
+
+	if len(choices) == 0 {
+		return nil, e.returnErrorOrPanic(errors.New("no choices given for the prompt: " + displayMessage))
+	}
+
+	for _, value := range options.DefaultSelection {
+		if !slices.Contains(choices, value) {
+			return nil, e.returnErrorOrPanic(fmt.Errorf("default %s is not one of the choices", value))
+		}
+	}
+
+	if !e.IsInteractive() {
+		if len(options.DefaultSelection) > 0 {
+			logrus.Infof("%s (non-interactive, using the defaults %s)", displayMessage,
+				strings.Join(options.DefaultSelection, ", "))
+			return options.DefaultSelection, nil
+		}
+
+		return nil, e.returnErrorOrPanic(&NonInteractiveError{Prompt: displayMessage})
+	}
+
+	for {
+		logrus.Info(displayMessage + " (comma separated)" + defaultHint(strings.Join(options.DefaultSelection, ",")))
+		logChoices(choices)
+
+		input, err := e.readLine()
+		if err != nil {
+			return nil, e.returnErrorOrPanic(err)
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" && len(options.DefaultSelection) > 0 {
+			return options.DefaultSelection, nil
+		}
+
+		selection, err := parseChoices(input, choices)
+		if err == nil {
+			return selection, nil
+		}
+
+		logrus.Warn(err)
+	}
+}
+
+func (e *executor) askUserForText(displayMessage string, options *PromptOptions, secret bool) (string, error) {
+	options = defaultPromptOptions(options)
+
+	if !e.IsInteractive() {
+		return e.nonInteractiveAnswer(displayMessage, options.Default)
+	}
+
+	hint := defaultHint(options.Default)
+	if secret && options.Default != "" {
+		hint = " [keep the default]"
+	}
+
+	for {
+		logrus.Info(displayMessage + hint)
+
+		var input string
+		var err error
+
+		if secret {
+			input, err = e.readSecretLine()
+		} else {
+			input, err = e.readLine()
+			input = strings.TrimSpace(input)
+		}
+
+		if err != nil {
+			return "", e.returnErrorOrPanic(err)
+		}
+
+		if input == "" {
+			input = options.Default
+		}
+
+		if options.Validate != nil {
+			if validationErr := options.Validate(input); validationErr != nil {
+				logrus.Warnf("Invalid input: %v", validationErr)
+				continue
+			}
+		}
+
+		if secret {
+			secrets.Register(input)
+		}
+
+		return input, nil
+	}
+}
+
+func (e *executor) nonInteractiveAnswer(displayMessage string, defaultValue string) (string, error) {
+	if defaultValue == "" {
+		return "", e.returnErrorOrPanic(&NonInteractiveError{Prompt: displayMessage})
+	}
+
+	logrus.Infof("%s (non-interactive, using the default)", displayMessage)
+	return defaultValue, nil
+}
+
+// readLine reads a single line from the stdin, without the line ending. Bytes are read one by one, so that nothing
+// after the line is consumed (consecutive prompts read from the same stdin).
+func (e *executor) readLine() (string, error) {
+	var line []byte
+	buffer := make([]byte, 1)
+
+	for {
+		n, err := e.stdin.Read(buffer)
+
+		if n > 0 {
+			if buffer[0] == '\n' {
+				break
+			}
+
+			line = append(line, buffer[0])
+		}
+
+		if err == io.EOF {
+			if len(line) == 0 {
+				return "", errors.New("no user input available, stdin was closed")
+			}
+
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+// readSecretLine reads a line without echoing it, if the stdin is a terminal
+func (e *executor) readSecretLine() (string, error) {
+	if file, ok := e.stdin.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		input, err := term.ReadPassword(int(file.Fd()))
+		fmt.Println()
+
+		return string(input), err
+	}
+
+	return e.readLine()
+}
+
+func defaultPromptOptions(options *PromptOptions) *PromptOptions {
+	if options == nil {
+		return &PromptOptions{}
+	}
+
+	return options
+}
+
+func defaultHint(defaultValue string) string {
+	if defaultValue == "" {
+		return ""
+	}
+
+	return " [" + defaultValue + "]"
+}
+
+func logChoices(choices []string) {
+	for i, choice := range choices {
+		logrus.Infof("  %d) %s", i+1, choice)
+	}
+}
+
+// parseChoice accepts either the number shown in front of the choice, or the choice itself
+func parseChoice(input string, choices []string) (string, error) {
+	if index, err := strconv.Atoi(input); err == nil && index >= 1 && index <= len(choices) {
+		return choices[index-1], nil
+	}
+
+	if slices.Contains(choices, input) {
+		return input, nil
+	}
+
+	return "", fmt.Errorf("%q is not one of the choices, please enter a number between 1 and %d", input, len(choices))
+}
+
+func parseChoices(input string, choices []string) ([]string, error) {
+	var selection []string
+
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		choice, err := parseChoice(part, choices)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(selection, choice) {
+			selection = append(selection, choice)
+		}
+	}
+
+	if len(selection) == 0 {
+		return nil, errors.New("please select at least one of the choices")
+	}
+
+	return selection, nil
+}
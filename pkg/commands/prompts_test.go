@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPromptTestExecutor(t *testing.T, userInput string) Executor {
+	logger := logging.Init(testLogFileName)
+	t.Cleanup(func() { os.Remove(testLogFileName) })
+
+	e := NewQuiet(testLogFileName, logger)
+	e.(*executor).OverrideStdIn(strings.NewReader(userInput))
+
+	return e
+}
+
+func Test_InputIsValidatedAndDefaultIsUsedForEmptyInput(t *testing.T) {
+	e := newPromptTestExecutor(t, "abc\n 42 \n\n")
+	numeric := &PromptOptions{Validate: func(input string) error {
+		_, err := strconv.Atoi(input)
+		return err
+	}}
+
+	// Act
+	number, err1 := e.AskUserForInput("Node count?", numeric)
+	color, err2 := e.AskUserForInput("Cluster color?", &PromptOptions{Default: "blue"})
+	_, err3 := e.AskUserForInput("Anything else?", nil)
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, "42", number)
+	assert.Equal(t, "blue", color)
+	assert.Error(t, err3, "stdin is exhausted")
+}
+
+func Test_ChoicesCanBeSelectedByNumberOrValue(t *testing.T) {
+	e := newPromptTestExecutor(t, "5\n2\nprod\n\n3, dev\n")
+	choices := []string{"dev", "test", "prod"}
+
+	// Act
+	first, err1 := e.AskUserToSelect("Environment?", choices, nil)
+	second, err2 := e.AskUserToSelect("Environment?", choices, nil)
+	third, err3 := e.AskUserToSelect("Environment?", choices, &PromptOptions{Default: "dev"})
+	multiple, err4 := e.AskUserToMultiSelect("Environments?", choices, nil)
+
+	// Assert
+	assert.NoError(t, errors.Join(err1, err2, err3, err4))
+	assert.Equal(t, "test", first, "invalid number 5 is asked again")
+	assert.Equal(t, "prod", second)
+	assert.Equal(t, "dev", third)
+	assert.Equal(t, []string{"prod", "dev"}, multiple)
+}
+
+func Test_SecretInputIsRegisteredAsSecret(t *testing.T) {
+	e := newPromptTestExecutor(t, "  prompted-secret-value\n")
+
+	// Act
+	secret, err := e.AskUserForSecret("Password?", nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "  prompted-secret-value", secret)
+	assert.Equal(t, "***", secrets.Redact(secret))
+}
+
+func Test_PromptsFailFastWhenNonInteractive(t *testing.T) {
+	e := newPromptTestExecutor(t, "ignored\n")
+	viper.Set("non-interactive", true)
+	defer viper.Set("non-interactive", false)
+
+	// Act
+	withDefault, err1 := e.AskUserToSelect("Color?", []string{"blue", "green"}, &PromptOptions{Default: "green"})
+	defaults, err2 := e.AskUserToMultiSelect("Colors?", []string{"blue", "green"},
+		&PromptOptions{DefaultSelection: []string{"blue"}})
+	_, err3 := e.AskUserForInput("Name?", nil)
+
+	// Assert
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, "green", withDefault)
+	assert.Equal(t, []string{"blue"}, defaults)
+
+	var nonInteractiveErr *NonInteractiveError
+	if assert.True(t, errors.As(err3, &nonInteractiveErr)) {
+		assert.Equal(t, "Name?", nonInteractiveErr.Prompt)
+	}
+}
+
+func Test_ConfirmationsAreAnsweredAutomaticallyOrFailWhenNonInteractive(t *testing.T) {
+	e := newPromptTestExecutor(t, "")
+	defer viper.Set("yes", false)
+	defer viper.Set("non-interactive", false)
+
+	// Act
+	viper.Set("non-interactive", true)
+	declined := e.AskUserToConfirm("Apply?")
+	_, nonInteractiveErr := e.AskUserToConfirmE("Apply?")
+
+	viper.Set("yes", true)
+	confirmed, err := e.AskUserToConfirmWithKeywordE("Type the cluster name to destroy it", "blue")
+
+	// Assert
+	assert.False(t, declined)
+	assert.ErrorContains(t, nonInteractiveErr, `"Apply? [yes|no]"`)
+	assert.NoError(t, err)
+	assert.True(t, confirmed)
+}
+
+func Test_CIIsDetectedFromEnvironmentVariables(t *testing.T) {
+	for _, variable := range []string{"CI", "TF_BUILD", "GITHUB_ACTIONS"} {
+		t.Setenv("CI", "")
+		t.Setenv("TF_BUILD", "")
+		t.Setenv("GITHUB_ACTIONS", "")
+		assert.False(t, IsCI())
+
+		t.Setenv(variable, "True")
+		assert.True(t, IsCI(), variable)
+	}
+}
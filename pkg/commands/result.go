@@ -0,0 +1,22 @@
+package commands
+
+import "time"
+
+// Result contains all the details of an executed command, as returned by Executor.Run and Executor.RunCmd
+type Result struct {
+	// Command is the executed command line, as shown in the logs
+	Command string
+
+	// Stdout is the complete stdout output of the command
+	Stdout string
+
+	// Stderr is the complete stderr output of the command
+	Stderr string
+
+	// ExitCode of the command. Set to -1 if the command could not be started, or was killed.
+	ExitCode int
+
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+}
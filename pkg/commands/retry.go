@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/avast/retry-go/v5"
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// AzureAuthorizationFailedPattern matches Azure errors caused by role assignments which are not yet propagated
+	// (usually resolved after a few minutes)
+	AzureAuthorizationFailedPattern = regexp.MustCompile(`AuthorizationFailed`)
+
+	// TerraformStateLockedPattern matches terraform errors caused by a state lock held by another terraform run
+	TerraformStateLockedPattern = regexp.MustCompile(`Error acquiring the state lock`)
+
+	// HelmOperationInProgressPattern matches helm errors caused by another install / upgrade / rollback of the release
+	HelmOperationInProgressPattern = regexp.MustCompile(`another operation \(install/upgrade/rollback\) is in progress`)
+)
+
+// RetryPolicy configures the retries of a failed command, see WithRetry. If no retry predicate is set (RetryOnExitCodes,
+// RetryOnStderr or RetryIf), every failure is retried. Otherwise, the command is retried if any of the predicates match.
+// Commands are never retried if the context of the command is cancelled or timed out, or if the command could not be
+// parsed.
+type RetryPolicy struct {
+	// Attempts is the maximum number of executions, including the first one. Values below 2 disable the retries.
+	Attempts uint
+
+	// Delay before the first retry, doubled for each further retry (exponential backoff). Defaults to one second.
+	Delay time.Duration
+
+	// MaxDelay limits the delay between two retries. Zero (default) means no limit.
+	MaxDelay time.Duration
+
+	// Jitter adds a random duration up to the given value to each delay, so that parallel commands failing for the same
+	// reason are not retried all at once. Zero (default) means no jitter.
+	Jitter time.Duration
+
+	// RetryOnExitCodes retries the command if it failed with one of the given exit codes
+	RetryOnExitCodes []int
+
+	// RetryOnStderr retries the command if its stderr output matches one of the given patterns (e.g.
+	// AzureAuthorizationFailedPattern, TerraformStateLockedPattern or HelmOperationInProgressPattern)
+	RetryOnStderr []*regexp.Regexp
+
+	// RetryIf retries the command if the function returns true for the result and the error of the failed attempt
+	RetryIf func(result *Result, err error) bool
+}
+
+func (policy *RetryPolicy) enabled() bool {
+	return policy != nil && policy.Attempts > 1
+}
+
+// ShouldRetry checks the failed attempt against the retry predicates of the policy (the number of attempts is not
+// checked). Useful for Executor implementations outside of this package, like commandstest.FakeExecutor.
+func (policy *RetryPolicy) ShouldRetry(ctx context.Context, result *Result, err error) bool {
+	var parseErr *ParseError
+	if ctx.Err() != nil || errors.As(err, &parseErr) || result == nil {
+		return false
+	}
+
+	if len(policy.RetryOnExitCodes) == 0 && len(policy.RetryOnStderr) == 0 && policy.RetryIf == nil {
+		return true
+	}
+
+	if slices.Contains(policy.RetryOnExitCodes, result.ExitCode) {
+		return true
+	}
+
+	for _, pattern := range policy.RetryOnStderr {
+		if pattern.MatchString(result.Stderr) {
+			return true
+		}
+	}
+
+	return policy.RetryIf != nil && policy.RetryIf(result, err)
+}
+
+// run is the non-panicking implementation of Run (command string given) and RunCmd (os/exec command given). The command
+// is retried according to the retry policy of the settings, every attempt runs a fresh copy of the given os/exec command
+// (a command cannot be started twice), and gets the same stdin input.
+func (e *executor) run(ctx context.Context, command string, cmd *exec.Cmd, settings *executeSettings) (*Result, error) {
+	policy := settings.retryPolicy
+
+	if !policy.enabled() {
+		return e.runOnce(ctx, command, cmd, settings)
+	}
+
+	var input []byte
+	if settings.stdin != nil {
+		var err error
+		input, err = io.ReadAll(settings.stdin)
+		if err != nil {
+			return &Result{Command: secrets.Redact(command), ExitCode: -1}, err
+		}
+	}
+
+	delay := policy.Delay
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	var result *Result
+
+	err := retry.New(
+		retry.Context(ctx),
+		retry.Attempts(policy.Attempts),
+		retry.Delay(delay),
+		retry.MaxDelay(policy.MaxDelay),
+		retry.MaxJitter(policy.Jitter),
+		retry.DelayType(retry.CombineDelay(retry.BackOffDelay, retry.RandomDelay)),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool {
+			return policy.ShouldRetry(ctx, result, err)
+		}),
+		retry.OnRetry(func(n uint, err error) {
+			if n+1 < policy.Attempts {
+				logrus.Warnf("[Retry] Attempt %d of %d failed for command %s, retrying. Error was: %v",
+					n+1, policy.Attempts, result.Command, err)
+			}
+		}),
+	).Do(func() error {
+		attemptCmd := cmd
+		if cmd != nil {
+			attemptCmd = copyCommand(cmd)
+		}
+
+		if settings.stdin != nil {
+			settings.stdin = bytes.NewReader(input)
+		}
+
+		var err error
+		result, err = e.runOnce(ctx, command, attemptCmd, settings)
+		return err
+	})
+
+	// cancellation while waiting for the next attempt
+	var timeoutErr *TimeoutError
+	if err != nil && ctx.Err() != nil && !errors.As(err, &timeoutErr) {
+		err = &TimeoutError{Command: result.Command, Stdout: secrets.Redact(result.Stdout),
+			Stderr: secrets.Redact(result.Stderr), Cause: ctx.Err()}
+	}
+
+	return result, err
+}
+
+// copyCommand creates a not yet started copy of the given os/exec command
+func copyCommand(cmd *exec.Cmd) *exec.Cmd {
+	copied := &exec.Cmd{
+		Path:       cmd.Path,
+		Args:       slices.Clone(cmd.Args),
+		Env:        slices.Clone(cmd.Env),
+		Dir:        cmd.Dir,
+		Stdin:      cmd.Stdin,
+		Stdout:     cmd.Stdout,
+		Stderr:     cmd.Stderr,
+		ExtraFiles: cmd.ExtraFiles,
+		Err:        cmd.Err,
+	}
+
+	if cmd.SysProcAttr != nil {
+		// process tree handling modifies the attributes, which should not affect the given command
+		attributes := *cmd.SysProcAttr
+		copied.SysProcAttr = &attributes
+	}
+
+	return copied
+}
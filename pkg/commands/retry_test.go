@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CommandIsRetriedWhenStderrMatches(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	// fails on the first attempt only
+	command := "sh -c \"if [ -f locked ]; then echo applied; else touch locked; echo 'Error acquiring the state lock' >&2; exit 1; fi\""
+
+	// Act
+	result, err := e.Run(context.Background(), command, WithDir(t.TempDir()), WithRetry(RetryPolicy{
+		Attempts:      3,
+		Delay:         time.Millisecond,
+		RetryOnStderr: []*regexp.Regexp{TerraformStateLockedPattern},
+	}))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "applied\n", result.Stdout)
+	testing_utils.CheckFileContainsString(t, testLogFileName, "Attempt 1 of 3 failed")
+}
+
+func Test_CommandIsNotRetriedWhenPredicatesDoNotMatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+	dir := t.TempDir()
+
+	// Act
+	result, err := e.Run(context.Background(), "sh -c \"echo attempt >> attempts; exit 1\"", WithDir(dir),
+		WithRetry(RetryPolicy{Attempts: 3, Delay: time.Millisecond, RetryOnExitCodes: []int{2}}))
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+
+	attempts, _ := os.ReadFile(filepath.Join(dir, "attempts"))
+	assert.Equal(t, 1, strings.Count(string(attempts), "attempt"))
+}
+
+func Test_EveryRetryGetsFreshCommandAndSameInput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+	dir := t.TempDir()
+	cmd := exec.Command("sh", "-c", "cat >> received; echo >> received; exit 3")
+
+	// Act
+	result, err := e.RunCmd(context.Background(), cmd, WithDir(dir), WithStdin(strings.NewReader("input")),
+		WithRetry(RetryPolicy{Attempts: 3, Delay: time.Millisecond, RetryOnExitCodes: []int{3}}))
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Nil(t, cmd.Process)
+
+	received, _ := os.ReadFile(filepath.Join(dir, "received"))
+	assert.Equal(t, "input\ninput\ninput\n", string(received))
+}
@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInterrupted is matched (errors.Is) by the errors of commands which were stopped because the program received a
+// shutdown signal, and of commands which were not started anymore afterwards (see HandleShutdownSignals)
+var ErrInterrupted = errors.New("interrupted by a shutdown signal")
+
+// shutdownPollInterval is the interval in which the running commands are checked during the grace period
+const shutdownPollInterval = 100 * time.Millisecond
+
+// interruptedExitCode is used to end the program if HandleShutdownSignals is called without exit function (128 + SIGINT,
+// same as hq.ExitCodeInterrupted)
+const interruptedExitCode = 130
+
+var (
+	shutdownMutex   sync.Mutex
+	handlingSignals bool
+	shuttingDown    bool
+	runningCommands = make(map[*exec.Cmd]struct{})
+)
+
+// HandleShutdownSignals handles SIGINT (Ctrl+C) and SIGTERM (e.g. a cancelled pipeline run) until the returned function
+// is called. The signal is forwarded to the commands running on all executors, so that they can end gracefully (like
+// terraform releasing its state lock). Commands still running after the grace period are killed, a second signal kills
+// them immediately. No new commands are started once a signal was received, they fail with ErrInterrupted.
+// If no command is running when the signal is received (e.g. the program waits for a prompt), or on a second signal,
+// the program is ended with the given exit function, which should run the cleanup and end the process. Nil exits
+// with exit code 130.
+// Used by hq.Run, programs using the executors without HQ can call it on their own.
+func HandleShutdownSignals(gracePeriod time.Duration, exit func()) (stop func()) {
+	if exit == nil {
+		exit = func() { os.Exit(interruptedExitCode) }
+	}
+
+	shutdownMutex.Lock()
+	handlingSignals = true
+	shutdownMutex.Unlock()
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		for {
+			select {
+			case received := <-signals:
+				if !beginShutdown() {
+					logrus.Warnf("Received %v again, killing the running commands and exiting", received)
+					killRunningCommands()
+					// not blocking the signal handling, in case the exit function hangs in a cleanup
+					go exit()
+					continue
+				}
+
+				if len(runningCommandsSnapshot()) == 0 {
+					logrus.Warnf("Received %v while no command is running, exiting", received)
+					go exit()
+					continue
+				}
+
+				logrus.Warnf("Received %v, stopping the running commands (grace period %v)", received, gracePeriod)
+				forwardToRunningCommands(received)
+				go killRunningCommandsAfter(gracePeriod, done)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			<-finished
+
+			shutdownMutex.Lock()
+			handlingSignals = false
+			shuttingDown = false
+			shutdownMutex.Unlock()
+		})
+	}
+}
+
+// beginShutdown marks the program as shutting down, returns false if it was already shutting down
+func beginShutdown() bool {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	if shuttingDown {
+		return false
+	}
+
+	shuttingDown = true
+	return true
+}
+
+func isShuttingDown() bool {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	return shuttingDown
+}
+
+// startsInOwnProcessGroup returns true if the commands have to be started in their own process group, so that signals
+// of the terminal (like Ctrl+C) only reach them once, forwarded by the signal handling
+func startsInOwnProcessGroup() bool {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	return handlingSignals
+}
+
+// refuseDuringShutdown returns ErrInterrupted for commands to be started after a shutdown signal was received
+func refuseDuringShutdown(command string) error {
+	if isShuttingDown() {
+		return fmt.Errorf("%w, command %s was not started", ErrInterrupted, command)
+	}
+
+	return nil
+}
+
+// trackRunningCommand registers a started command for the signal handling, the returned function unregisters it once
+// the command ended
+func trackRunningCommand(cmd *exec.Cmd) (untrack func()) {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	runningCommands[cmd] = struct{}{}
+
+	return func() {
+		shutdownMutex.Lock()
+		defer shutdownMutex.Unlock()
+
+		delete(runningCommands, cmd)
+	}
+}
+
+func runningCommandsSnapshot() []*exec.Cmd {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	commands := make([]*exec.Cmd, 0, len(runningCommands))
+	for cmd := range runningCommands {
+		commands = append(commands, cmd)
+	}
+
+	return commands
+}
+
+func forwardToRunningCommands(received os.Signal) {
+	for _, cmd := range runningCommandsSnapshot() {
+		if err := signalProcessTree(cmd, received); err != nil {
+			logrus.Debugf("could not forward %v to the command %s: %v", received, cmd.Path, err)
+		}
+	}
+}
+
+func killRunningCommands() {
+	for _, cmd := range runningCommandsSnapshot() {
+		killProcessTree(cmd)
+	}
+}
+
+// killRunningCommandsAfter kills the commands still running after the grace period
+func killRunningCommandsAfter(gracePeriod time.Duration, done <-chan struct{}) {
+	deadline := time.After(gracePeriod)
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if len(runningCommandsSnapshot()) == 0 {
+				return
+			}
+		case <-deadline:
+			if running := runningCommandsSnapshot(); len(running) > 0 {
+				logrus.Warnf("Killing %d command(s) still running after the grace period", len(running))
+				killRunningCommands()
+			}
+			return
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ShutdownSignalIsForwardedToRunningCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to other processes on Windows")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	stop := HandleShutdownSignals(10*time.Second, nil)
+	defer stop()
+
+	sendInterruptToSelfAfter(t, 500*time.Millisecond)
+
+	// Act
+	startTime := time.Now()
+	_, err := e.Execute("bash -c \"trap 'echo released the lock; exit 1' INT; sleep 10 >/dev/null 2>&1 & wait\"")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Contains(t, err.Error(), "released the lock")
+	assert.Less(t, time.Since(startTime), 5*time.Second)
+
+	_, err = e.Execute("go version")
+	assert.ErrorIs(t, err, ErrInterrupted)
+}
+
+func Test_CommandsStillRunningAfterGracePeriodAreKilled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to other processes on Windows")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	stop := HandleShutdownSignals(200*time.Millisecond, nil)
+	sendInterruptToSelfAfter(t, 500*time.Millisecond)
+
+	// Act
+	startTime := time.Now()
+	_, err := e.Execute("bash -c \"trap '' INT; sleep 10\"")
+	stop()
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Less(t, time.Since(startTime), 5*time.Second)
+
+	_, err = e.Execute("go version")
+	assert.NoError(t, err, "commands are started again once the signal handling is stopped")
+}
+
+func Test_ShutdownSignalWithoutRunningCommandsExits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to other processes on Windows")
+	}
+
+	exited := make(chan struct{}, 2)
+	stop := HandleShutdownSignals(10*time.Second, func() { exited <- struct{}{} })
+	defer stop()
+
+	// Act
+	sendInterruptToSelfAfter(t, 0)
+
+	// Assert
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the program was not ended, although no command was running")
+	}
+}
+
+func Test_SecondShutdownSignalExits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to other processes on Windows")
+	}
+
+	logger := logging.Init(testLogFileName)
+	defer os.Remove(testLogFileName)
+	e := NewQuiet(testLogFileName, logger)
+
+	exited := make(chan struct{}, 2)
+	stop := HandleShutdownSignals(10*time.Second, func() { exited <- struct{}{} })
+	defer stop()
+
+	sendInterruptToSelfAfter(t, 500*time.Millisecond)
+	sendInterruptToSelfAfter(t, time.Second)
+
+	// Act
+	startTime := time.Now()
+	_, err := e.Execute("bash -c \"trap '' INT; sleep 10\"")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Less(t, time.Since(startTime), 5*time.Second)
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the program was not ended on the second signal")
+	}
+}
+
+func sendInterruptToSelfAfter(t *testing.T, delay time.Duration) {
+	go func() {
+		// gives the command time to start and install its signal handling
+		time.Sleep(delay)
+
+		process, _ := os.FindProcess(os.Getpid())
+		assert.NoError(t, process.Signal(os.Interrupt))
+	}()
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// taskOutput redirects the console and log file output of a task executed via Executor.ExecuteParallel, so that the
+// output of concurrently running tasks is not interleaved. Either the output is buffered and written as a labelled
+// block once the task finished, or every line is written immediately, prefixed with the task name (stream mode).
+type taskOutput struct {
+	name   string
+	stream bool
+
+	// shared by all tasks of the same parallel execution
+	lock *sync.Mutex
+
+	consoleTarget io.Writer
+	logFileTarget io.Writer
+	console       lockedBuffer
+	logFile       lockedBuffer
+	lineWriters   []*lineWriter
+}
+
+// wrap replaces the sinks of the task. Discarded sinks stay discarded.
+func (o *taskOutput) wrap(stdout io.Writer, stderr io.Writer, logFileStdout io.Writer, logFileStderr io.Writer) (io.Writer, io.Writer, io.Writer, io.Writer) {
+	if o.stream {
+		return o.prefixLines(stdout), o.prefixLines(stderr), o.prefixLines(logFileStdout), o.prefixLines(logFileStderr)
+	}
+
+	o.consoleTarget = stdout
+	o.logFileTarget = logFileStdout
+
+	// stdout and stderr are combined into one block, same as they would appear on the console
+	return o.buffer(stdout, &o.console), o.buffer(stderr, &o.console), o.buffer(logFileStdout, &o.logFile),
+		o.buffer(logFileStderr, &o.logFile)
+}
+
+// finish writes the buffered block, or the last incomplete lines in stream mode
+func (o *taskOutput) finish() {
+	for _, writer := range o.lineWriters {
+		writer.flush()
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	writeBlock(o.consoleTarget, o.name, o.console.Bytes())
+	writeBlock(o.logFileTarget, o.name, o.logFile.Bytes())
+}
+
+func (o *taskOutput) prefixLines(writer io.Writer) io.Writer {
+	if writer == io.Discard {
+		return writer
+	}
+
+	// each line is written while holding the shared lock, so that lines of different tasks are not mixed
+	lineWriter := newLineWriter(func(line string) {
+		o.lock.Lock()
+		defer o.lock.Unlock()
+
+		io.WriteString(writer, "["+o.name+"] "+line+"\n")
+	})
+	o.lineWriters = append(o.lineWriters, lineWriter)
+
+	return lineWriter
+}
+
+func (o *taskOutput) buffer(writer io.Writer, buffer *lockedBuffer) io.Writer {
+	if writer == io.Discard {
+		return writer
+	}
+
+	return buffer
+}
+
+func writeBlock(writer io.Writer, name string, content []byte) {
+	if writer == nil || len(content) == 0 {
+		return
+	}
+
+	io.WriteString(writer, "----- ["+name+"] -----\n")
+	writer.Write(content)
+
+	if content[len(content)-1] != '\n' {
+		io.WriteString(writer, "\n")
+	}
+}
+
+// lockedBuffer is a bytes.Buffer which can be written concurrently (by the stdout and stderr copy routines)
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buffer.Bytes()
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits the command string into arguments, similar to a POSIX shell (without any variable expansion, globbing
+// or other shell features). The grammar is:
+//   - arguments are separated by unquoted whitespace (spaces, tabs, line breaks)
+//   - "double quotes" group everything in between into one argument. Within double quotes, a backslash only escapes
+//     a double quote (\") or a backslash (\\), all other backslashes are kept as they are (e.g. in Windows paths)
+//   - 'single quotes' group everything in between into one argument, without any escaping
+//   - quotes are only recognized at the start of an argument, or directly after the equal sign of a --flag= or key=
+//     prefix, so that --flag="a b" results in the argument --flag=a b. Quotes anywhere else are kept as they are, so
+//     that arguments like object["property"] or the JMESPath query [?name=='x'] remain unchanged
+//   - outside of quotes, a backslash escapes a following whitespace or quote character. All other backslashes are kept
+//     as they are
+//   - empty double or single quotes result in an empty argument
+//
+// An unbalanced quote results in a *ParseError.
+func Tokenize(command string) ([]string, error) {
+	return tokenize(command, false, false)
+}
+
+// TokenizeStrict is same as Tokenize, but additionally rejects ambiguous input with a *ParseError: quotes which are kept
+// as they are (like in object["property"]), and text directly following a closing quote (like in "a"b).
+func TokenizeStrict(command string) ([]string, error) {
+	return tokenize(command, true, false)
+}
+
+func tokenize(command string, strict bool, lenient bool) ([]string, error) {
+	runes := []rune(command)
+	args := []string{}
+
+	var current strings.Builder
+	inArgument := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			if inArgument {
+				args = append(args, current.String())
+				current.Reset()
+				inArgument = false
+			}
+
+		case r == '\\' && i+1 < len(runes) && (unicode.IsSpace(runes[i+1]) || isQuote(runes[i+1])):
+			current.WriteRune(runes[i+1])
+			inArgument = true
+			i++
+
+		case isQuote(r) && (!inArgument || runes[i-1] == '=' && isKeyPrefix(current.String())):
+			closingQuote, err := readQuoted(command, runes, i, &current, lenient)
+			if err != nil {
+				return nil, err
+			}
+
+			if strict && closingQuote+1 < len(runes) && !unicode.IsSpace(runes[closingQuote+1]) {
+				return nil, &ParseError{Command: command, Position: closingQuote + 1,
+					Reason: "text directly following a closing quote is ambiguous"}
+			}
+
+			inArgument = true
+			i = closingQuote
+
+		default:
+			if strict && isQuote(r) {
+				return nil, &ParseError{Command: command, Position: i,
+					Reason: "quote within an argument is ambiguous, it should be escaped or the whole argument quoted"}
+			}
+
+			current.WriteRune(r)
+			inArgument = true
+		}
+	}
+
+	if inArgument {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// readQuoted writes the quoted text starting at the given opening quote to the builder, and returns the position of the
+// closing quote. In lenient mode, an unbalanced quote is closed at the end of the command.
+func readQuoted(command string, runes []rune, openingQuote int, builder *strings.Builder, lenient bool) (int, error) {
+	quote := runes[openingQuote]
+
+	for i := openingQuote + 1; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote == '"' && r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+			builder.WriteRune(runes[i+1])
+			i++
+			continue
+		}
+
+		if r == quote {
+			return i, nil
+		}
+
+		builder.WriteRune(r)
+	}
+
+	if lenient {
+		return len(runes) - 1, nil
+	}
+
+	return 0, &ParseError{Command: command, Position: openingQuote, Reason: "unbalanced quote"}
+}
+
+// isKeyPrefix returns true if the argument text is a --flag= or key= prefix (like --set=, name= or image.tag=)
+func isKeyPrefix(text string) bool {
+	key := strings.TrimLeft(strings.TrimSuffix(text, "="), "-")
+	if key == "" {
+		return false
+	}
+
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isQuote(r rune) bool {
+	return r == '"' || r == '\''
+}
@@ -5,4 +5,6 @@ package error_handling
 // everything to stdout/stderr, and the error will be written into logs too). This mode might be interesting for code
 // equivalent to Bash scripts running with 'set -e'. Setting panic mode only affects the commands / methods executed
 // after calling this method, and the mode can also be reverted by setting to false.
+// The flag is only the default for executors and HQ instances created without an explicit Policy. Don't toggle it for
+// calls which are expected to fail, use commands.Executor.Try instead.
 var PanicOnAnyError = false
@@ -0,0 +1,30 @@
+package error_handling
+
+// Policy defines how the errors of cops-hq methods are raised. Other than the global PanicOnAnyError flag, a policy is
+// carried by a single executor (see commands.ExecutorOptions.ErrorPolicy) or HQ instance (see hq.HqOptions.ErrorPolicy),
+// and applies to that instance and to the recipes using it only. Methods not bound to an executor (like the naming
+// service) always follow the global flag.
+type Policy int
+
+const (
+	// Default follows the global PanicOnAnyError flag, at the time the error occurs
+	Default Policy = iota
+
+	// Panic issues a panic on any error, regardless of the global PanicOnAnyError flag
+	Panic
+
+	// Return returns all errors to the caller, regardless of the global PanicOnAnyError flag
+	Return
+)
+
+// PanicsOnError returns true if errors are raised as panic with this policy
+func (p Policy) PanicsOnError() bool {
+	switch p {
+	case Panic:
+		return true
+	case Return:
+		return false
+	default:
+		return PanicOnAnyError
+	}
+}
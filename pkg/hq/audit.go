@@ -0,0 +1,78 @@
+package hq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+)
+
+// auditFilter selects the audit entries shown by the in-built 'hq audit show' command. Empty fields match all entries.
+type auditFilter struct {
+	contains   string
+	user       string
+	since      time.Duration
+	failedOnly bool
+}
+
+func (filter auditFilter) matches(entry commands.AuditEntry, now time.Time) bool {
+	if filter.contains != "" && !strings.Contains(entry.Command, filter.contains) {
+		return false
+	}
+
+	if filter.user != "" && entry.User != filter.user {
+		return false
+	}
+
+	if filter.since > 0 && entry.Timestamp.Before(now.Add(-filter.since)) {
+		return false
+	}
+
+	return !filter.failedOnly || entry.ExitCode != 0
+}
+
+// showAuditEntries writes the matching entries of the audit file either as a table, or as the original JSON lines
+func showAuditEntries(auditFileName string, filter auditFilter, asJson bool, out io.Writer) error {
+	entries, err := commands.ReadAuditEntries(auditFileName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	if !asJson {
+		fmt.Fprintln(table, "TIMESTAMP\tUSER\tPROGRAM\tSUBSCRIPTION\tEXIT CODE\tDURATION\tCOMMAND")
+	}
+
+	for _, entry := range entries {
+		if !filter.matches(entry, now) {
+			continue
+		}
+
+		if asJson {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, string(line))
+			continue
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			entry.Timestamp.Local().Format(time.DateTime),
+			entry.User,
+			strings.TrimSpace(entry.Program+" "+entry.Version),
+			entry.Subscription,
+			entry.ExitCode,
+			(time.Duration(entry.DurationMs) * time.Millisecond).String(),
+			entry.Command)
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,68 @@
+package hq
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AuditEntriesAreFiltered(t *testing.T) {
+	// Arrange
+	auditFileName := filepath.Join(t.TempDir(), "audit.jsonl")
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	old := time.Now().Add(-72 * time.Hour).UTC().Format(time.RFC3339)
+
+	os.WriteFile(auditFileName, []byte(
+		`{"timestamp":"`+old+`","user":"alice","program":"infra","version":"1.0.0","command":"terraform apply","exitCode":0}`+"\n"+
+			`{"timestamp":"`+recent+`","user":"alice","program":"infra","version":"1.0.0","command":"terraform plan","exitCode":1}`+"\n"+
+			`{"timestamp":"`+recent+`","user":"bob","program":"infra","version":"1.0.0","command":"terraform apply","exitCode":0}`+"\n"),
+		0644)
+
+	tests := []struct {
+		name             string
+		filter           auditFilter
+		expectedCommands int
+	}{
+		{"no filter", auditFilter{}, 3},
+		{"contains", auditFilter{contains: "apply"}, 2},
+		{"user", auditFilter{user: "alice"}, 2},
+		{"since", auditFilter{since: 24 * time.Hour}, 2},
+		{"failed only", auditFilter{failedOnly: true}, 1},
+		{"combined", auditFilter{contains: "apply", since: 24 * time.Hour}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			// Act
+			err := showAuditEntries(auditFileName, tt.filter, true, &out)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCommands, strings.Count(out.String(), "\n"))
+		})
+	}
+}
+
+func Test_AuditEntriesAreShownAsTable(t *testing.T) {
+	// Arrange
+	auditFileName := filepath.Join(t.TempDir(), "audit.jsonl")
+	os.WriteFile(auditFileName, []byte(`{"timestamp":"2026-01-02T10:00:00Z","user":"alice","program":"infra",`+
+		`"version":"1.0.0","command":"terraform apply","subscription":"sub","exitCode":2,"durationMs":1500}`+"\n"), 0644)
+
+	var out bytes.Buffer
+
+	// Act
+	err := showAuditEntries(auditFileName, auditFilter{}, false, &out)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "EXIT CODE")
+	assert.Regexp(t, `alice\s+infra 1\.0\.0\s+sub\s+2\s+1\.5s\s+terraform apply`, out.String())
+}
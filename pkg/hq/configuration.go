@@ -13,17 +13,19 @@ func (hq *hqContainer) LoadConfigFile(filePath string) error {
 
 	// this should be kept as ExecuteSilent for security reasons, not to leak the whole config file in plaintext
 	// to the log file!
-	configFile, err := hq.Executor.ExecuteSilent("sops -d " + filePath)
+	configFile, err := hq.Executor.ReadOnly().ExecuteSilent("sops -d " + filePath)
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(fmt.Errorf("error recieved while reading the config file: %w", err))
+		return internal.ReturnErrorOrPanicWith(hq.Executor.ErrorPolicy(),
+			fmt.Errorf("error recieved while reading the config file: %w", err))
 	}
 
 	viper.SetConfigType("yaml")
 	err = viper.MergeConfig(strings.NewReader(configFile))
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(fmt.Errorf("error recieved while reading the config file: %w", err))
+		return internal.ReturnErrorOrPanicWith(hq.Executor.ErrorPolicy(),
+			fmt.Errorf("error recieved while reading the config file: %w", err))
 	}
 
 	hq.RawConfiguration = configFile
@@ -37,7 +39,7 @@ func (hq *hqContainer) LoadEnvironmentConfigFile() error {
 
 func (hq *hqContainer) GetRawConfigurationFile() (string, error) {
 	if hq.RawConfiguration == "" {
-		return "", internal.ReturnErrorOrPanic(fmt.Errorf("configuration was not loaded yet. load configfile first."))
+		return "", internal.ReturnErrorOrPanicWith(hq.Executor.ErrorPolicy(), ErrConfigurationNotLoaded)
 	}
 
 	return hq.RawConfiguration, nil
@@ -1,11 +1,18 @@
 package hq
 
 import (
-	"github.com/conplementag/cops-hq/v2/internal"
+	"errors"
+	"github.com/conplementag/cops-hq/v2/internal/ci"
+	"github.com/conplementag/cops-hq/v2/internal/logging"
 	"github.com/conplementag/cops-hq/v2/pkg/cli"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
 	"github.com/sirupsen/logrus"
+	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
 // ProjectBasePath simply points to root of the Go project, which should always be two levels above
@@ -17,11 +24,119 @@ type hqContainer struct {
 	Cli              cli.Cli
 	Logger           *logrus.Logger
 	RawConfiguration string
+	AuditFileName    string
+
+	ShowRunSummary             bool
+	RunSummaryMarkdownFileName string
+	RunSummaryJsonFileName     string
+
+	ShutdownGracePeriod time.Duration
+
+	shutdownMutex sync.Mutex
+	shutdownHooks []func()
 }
 
+// exit ends the process with the given exit code, replaced in tests
+var exit = os.Exit
+
 func (hq *hqContainer) Run() error {
-	err := hq.Cli.Run()
-	return internal.ReturnErrorOrPanic(err)
+	stopSignalHandling := commands.HandleShutdownSignals(hq.ShutdownGracePeriod, func() {
+		// the run is blocked outside of a command (e.g. in a prompt), or the user insists on ending it
+		hq.runShutdownHooks()
+		exit(ExitCodeInterrupted)
+	})
+
+	panicked, err := hq.runCli()
+	hq.runShutdownHooks()
+	stopSignalHandling()
+
+	hq.reportRunSummary(err)
+
+	// the run summary and the final error are logged after the shutdown hooks
+	flushLogFiles()
+
+	if err != nil && (panicked || hq.Executor.ErrorPolicy().PanicsOnError()) {
+		// the error is already logged, and ends the program with the exit code matching the error (instead of a panic
+		// with a stack trace)
+		exit(ExitCode(err))
+	}
+
+	return err
+}
+
+func (hq *hqContainer) RunAndExit() {
+	// in panic mode, Run already ends the program on errors
+	if err := hq.Run(); err != nil {
+		exit(ExitCode(err))
+	}
+}
+
+// runCli runs the CLI, and recovers all panics raised on the calling goroutine. Errors raised as panic in panic mode
+// (see HqOptions.ErrorPolicy) are returned as they are, other panics (like runtime errors) as PanicError.
+func (hq *hqContainer) runCli() (panicked bool, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		panicked = true
+
+		recoveredErr, isError := recovered.(error)
+		var runtimeErr runtime.Error
+
+		if isError && !errors.As(recoveredErr, &runtimeErr) {
+			// already logged when raised
+			err = recoveredErr
+			return
+		}
+
+		panicErr := &PanicError{Value: recovered, Stack: debug.Stack()}
+		logrus.Error(panicErr)
+		logrus.Debugf("Stack trace of the panic:\n%s", panicErr.Stack)
+		ci.AnnotateError(panicErr)
+
+		err = panicErr
+	}()
+
+	return false, hq.Cli.Run()
+}
+
+func (hq *hqContainer) OnShutdown(hook func()) {
+	hq.shutdownMutex.Lock()
+	defer hq.shutdownMutex.Unlock()
+
+	hq.shutdownHooks = append(hq.shutdownHooks, hook)
+}
+
+// runShutdownHooks calls the registered hooks in reverse order. A failing hook is logged, and does not prevent the
+// other hooks from running.
+func (hq *hqContainer) runShutdownHooks() {
+	hq.shutdownMutex.Lock()
+	hooks := hq.shutdownHooks
+	hq.shutdownHooks = nil
+	hq.shutdownMutex.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runShutdownHook(hooks[i])
+	}
+}
+
+// flushLogFiles writes out the buffered writes of the log file, registered as the last shutdown hook
+func flushLogFiles() {
+	if err := logging.FlushLogFiles(); err != nil {
+		logrus.Warnf("could not flush the log file: %v", err)
+	}
+}
+
+func runShutdownHook(hook func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logrus.Errorf("shutdown hook failed: %v", recovered)
+		}
+	}()
+
+	hook()
 }
 
 func (hq *hqContainer) GetExecutor() commands.Executor {
@@ -35,3 +150,7 @@ func (hq *hqContainer) GetCli() cli.Cli {
 func (hq *hqContainer) GetLogrusLogger() *logrus.Logger {
 	return hq.Logger
 }
+
+func (hq *hqContainer) RegisterSecret(value string) {
+	hq.Executor.RegisterSecret(value)
+}
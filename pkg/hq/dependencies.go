@@ -2,13 +2,14 @@ package hq
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 
 	semver "github.com/Masterminds/semver/v3"
 	"github.com/conplementag/cops-hq/v2/internal"
-	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
+	"github.com/conplementag/cops-hq/v2/internal/ci"
 	"github.com/sirupsen/logrus"
 )
 
@@ -42,14 +43,15 @@ func (hq *hqContainer) CheckToolingDependencies() error {
 	err6 := hq.checkCopsctl()
 
 	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil {
-		compositeErr := fmt.Errorf("mandatory tooling dependencies check failed: %v %v %v %v %v %v", err1, err2, err3, err4, err5, err6)
-		return internal.ReturnErrorOrPanic(compositeErr)
+		compositeErr := fmt.Errorf("%w: %w", ErrToolingDependencies, errors.Join(err1, err2, err3, err4, err5, err6))
+		return internal.ReturnErrorOrPanicWith(hq.Executor.ErrorPolicy(), compositeErr)
 	}
 
 	// optional but recommended dependencies
 	warn1 := hq.checkSops()
 
 	if warn1 != nil {
+		ci.AnnotateWarning(fmt.Sprintf("Sops - optional dependency (recommended to be installed) not met: %v", warn1))
 		logrus.Warnf("Sops - optional dependency (recommended to be installed) not met: %v", warn1)
 		logrus.Warn("Sops is a useful tool for source version configuration management.")
 	}
@@ -57,6 +59,7 @@ func (hq *hqContainer) CheckToolingDependencies() error {
 	warn2 := hq.checkVim()
 
 	if warn2 != nil {
+		ci.AnnotateWarning(fmt.Sprintf("Vim - optional dependency (recommended to be installed) not met: %v", warn2))
 		logrus.Warnf("Vim - optional dependency (recommended to be installed) not met: %v", warn2)
 		logrus.Warn("Vim is used as the default editor for some cops-hq functionality, like fixing MAC versions " +
 			"of Sops managed config files.")
@@ -67,7 +70,7 @@ func (hq *hqContainer) CheckToolingDependencies() error {
 
 func (hq *hqContainer) checkAzureCli() error {
 	logrus.Info("Checking azure cli...")
-	azureCliVersion, err := hq.Executor.Execute("az version -o json")
+	azureCliVersion, err := hq.Executor.ReadOnly().Execute("az version -o json")
 
 	if err != nil {
 		return err
@@ -84,7 +87,7 @@ func (hq *hqContainer) checkAzureCli() error {
 	installedVersion, _ := semver.NewVersion(response.AzureCli)
 
 	if !versionConstraint.Check(installedVersion) {
-		return fmt.Errorf("azure cli version mismatch. expected >= %v, got %v", ExpectedMinAzureCliVersion, installedVersion)
+		return &ToolVersionError{Tool: "azure cli", ExpectedVersion: ExpectedMinAzureCliVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 	}
 
 	logrus.Info("...ok.")
@@ -93,7 +96,7 @@ func (hq *hqContainer) checkAzureCli() error {
 
 func (hq *hqContainer) checkHelm() error {
 	logrus.Info("Checking helm...")
-	helmVersion, err := hq.Executor.Execute("helm version --template={{.Version}}")
+	helmVersion, err := hq.Executor.ReadOnly().Execute("helm version --template={{.Version}}")
 
 	if err != nil {
 		return err
@@ -105,7 +108,7 @@ func (hq *hqContainer) checkHelm() error {
 	installedVersion, _ := semver.NewVersion(helmVersion)
 
 	if !versionConstraint.Check(installedVersion) {
-		return fmt.Errorf("helm version mismatch. expected >= %v, got %v", ExpectedMinHelmVersion, installedVersion)
+		return &ToolVersionError{Tool: "helm", ExpectedVersion: ExpectedMinHelmVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 	}
 
 	logrus.Info("...ok.")
@@ -114,7 +117,7 @@ func (hq *hqContainer) checkHelm() error {
 
 func (hq *hqContainer) checkTerraform() error {
 	logrus.Info("Checking terraform...")
-	terraformVersion, err := hq.Executor.Execute("terraform --version -json")
+	terraformVersion, err := hq.Executor.ReadOnly().Execute("terraform --version -json")
 
 	if err != nil {
 		return err
@@ -131,7 +134,7 @@ func (hq *hqContainer) checkTerraform() error {
 	installedVersion, _ := semver.NewVersion(terraformResponse.TerraformVersion)
 
 	if !versionConstraint.Check(installedVersion) {
-		return fmt.Errorf("terraform version mismatch. expected >= %v, got %v", ExpectedMinTerraformVersion, installedVersion)
+		return &ToolVersionError{Tool: "terraform", ExpectedVersion: ExpectedMinTerraformVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 	}
 
 	logrus.Info("...ok.")
@@ -140,7 +143,7 @@ func (hq *hqContainer) checkTerraform() error {
 
 func (hq *hqContainer) checkKubectl() error {
 	logrus.Info("Checking kubectl...")
-	kubectlVersion, err := hq.Executor.Execute("kubectl version --client=true -o json")
+	kubectlVersion, err := hq.Executor.ReadOnly().Execute("kubectl version --client=true -o json")
 
 	if err != nil {
 		return err
@@ -157,7 +160,7 @@ func (hq *hqContainer) checkKubectl() error {
 	installedVersion, err := semver.NewVersion(kubectlResponse.ClientVersion.GitVersion)
 
 	if !versionConstraint.Check(installedVersion) {
-		return fmt.Errorf("kubectl version mismatch. expected >= %v, got %v", ExpectedMinKubectlVersion, installedVersion)
+		return &ToolVersionError{Tool: "kubectl", ExpectedVersion: ExpectedMinKubectlVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 	}
 
 	logrus.Info("...ok.")
@@ -166,7 +169,7 @@ func (hq *hqContainer) checkKubectl() error {
 
 func (hq *hqContainer) checkKubelogin() error {
 	logrus.Info("Checking kubelogin...")
-	kubeloginVersion, err := hq.Executor.Execute("kubelogin --version")
+	kubeloginVersion, err := hq.Executor.ReadOnly().Execute("kubelogin --version")
 
 	if err != nil {
 		return err
@@ -180,7 +183,7 @@ func (hq *hqContainer) checkKubelogin() error {
 		installedVersion, _ := semver.NewVersion(matches[1])
 
 		if !versionConstraint.Check(installedVersion) {
-			return fmt.Errorf("kubelogin version mismatch. expected >= %v, got %v", ExpectedMinKubeloginVersion, installedVersion)
+			return &ToolVersionError{Tool: "kubelogin", ExpectedVersion: ExpectedMinKubeloginVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 		}
 	} else {
 		return fmt.Errorf("kubelogin version could not be parsed from this output: %s", kubeloginVersion)
@@ -192,7 +195,7 @@ func (hq *hqContainer) checkKubelogin() error {
 
 func (hq *hqContainer) checkCopsctl() error {
 	logrus.Info("Checking copsctl...")
-	copsctlVersion, err := hq.Executor.Execute("copsctl --version")
+	copsctlVersion, err := hq.Executor.ReadOnly().Execute("copsctl --version")
 
 	if err != nil {
 		return err
@@ -204,7 +207,7 @@ func (hq *hqContainer) checkCopsctl() error {
 	installedVersion, _ := semver.NewVersion(copsctlVersion)
 
 	if !versionConstraint.Check(installedVersion) {
-		return fmt.Errorf("copsctl version mismatch. expected >= %v, got %v", ExpectedMinCopsctlVersion, installedVersion)
+		return &ToolVersionError{Tool: "copsctl", ExpectedVersion: ExpectedMinCopsctlVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 	}
 
 	logrus.Info("...ok.")
@@ -215,12 +218,7 @@ func (hq *hqContainer) checkSops() error {
 	logrus.Info("Checking sops...")
 
 	// sops is an optional dependency, so in case we are in panic mode, we should survive it
-	previousPanicSetting := error_handling.PanicOnAnyError
-	error_handling.PanicOnAnyError = false
-
-	sopsVersion, err := hq.Executor.Execute("sops --version")
-
-	error_handling.PanicOnAnyError = previousPanicSetting
+	sopsVersion, err := hq.Executor.ReadOnly().Try().Execute("sops --version")
 
 	if err != nil {
 		return err
@@ -234,7 +232,7 @@ func (hq *hqContainer) checkSops() error {
 		installedVersion, _ := semver.NewVersion(matches[1])
 
 		if installedVersion == nil || !versionConstraint.Check(installedVersion) {
-			return fmt.Errorf("sops version mismatch. expected >= %v, got %v", ExpectedMinSopsVersion, installedVersion)
+			return &ToolVersionError{Tool: "sops", ExpectedVersion: ExpectedMinSopsVersion, InstalledVersion: fmt.Sprint(installedVersion)}
 		}
 	} else {
 		return fmt.Errorf("sops version could not be parsed from this output: %s", sopsVersion)
@@ -247,15 +245,10 @@ func (hq *hqContainer) checkSops() error {
 func (hq *hqContainer) checkVim() error {
 	logrus.Info("Checking vim...")
 
-	// Vim is an optional dependency, so in case we are in panic mode, we should survive it
-	previousPanicSetting := error_handling.PanicOnAnyError
-	error_handling.PanicOnAnyError = false
-
+	// Vim is an optional dependency, so in case we are in panic mode, we should survive it.
 	// Result is ignored, because we simply need to check if installed, which should return no errors.
 	// Checking for the correct version like for other dependencies is not required here.
-	_, err := hq.Executor.Execute("vim --version")
-
-	error_handling.PanicOnAnyError = previousPanicSetting
+	_, err := hq.Executor.ReadOnly().Try().Execute("vim --version")
 
 	if err != nil {
 		logrus.Info("...ok.")
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"strings"
@@ -64,6 +65,18 @@ func (e *versionCheckExecutorMock) SetVersionsToExpected() {
 	e.copsctlVersion = ExpectedMinCopsctlVersion
 }
 
+func (e *versionCheckExecutorMock) ReadOnly() commands.Executor {
+	return e
+}
+
+func (e *versionCheckExecutorMock) Try() commands.Executor {
+	return e
+}
+
+func (e *versionCheckExecutorMock) ErrorPolicy() error_handling.Policy {
+	return error_handling.Default
+}
+
 func (e *versionCheckExecutorMock) Execute(command string) (string, error) {
 	e.Called(command)
 
@@ -0,0 +1,48 @@
+package hq
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrToolingDependencies is matched (errors.Is) by the error of CheckToolingDependencies, if a mandatory tool is
+	// missing or outdated. Outdated tools are reported as ToolVersionError, missing tools as exec.ErrNotFound.
+	ErrToolingDependencies = errors.New("mandatory tooling dependencies check failed")
+
+	// ErrConfigurationNotLoaded is returned by GetRawConfigurationFile, if no configuration file was loaded yet
+	ErrConfigurationNotLoaded = errors.New("configuration was not loaded yet. load configfile first.")
+)
+
+// ToolVersionError is returned by the tooling dependencies check, if a tool is older than the expected minimal version
+type ToolVersionError struct {
+	// Tool is the name of the tool, e.g. "terraform"
+	Tool string
+
+	ExpectedVersion  string
+	InstalledVersion string
+}
+
+func (e *ToolVersionError) Error() string {
+	return fmt.Sprintf("%s version mismatch. expected >= %v, got %v", e.Tool, e.ExpectedVersion, e.InstalledVersion)
+}
+
+// PanicError is returned by Run for panics which are not raised errors (like runtime errors), so that the program ends
+// with a logged error instead of a crash with a stack trace
+type PanicError struct {
+	// Value is the recovered value of the panic
+	Value any
+
+	// Stack is the stack trace of the panic
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("unexpected panic: %v", e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error (like a runtime.Error), nil otherwise
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
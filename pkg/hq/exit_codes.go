@@ -0,0 +1,92 @@
+package hq
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/naming"
+	"github.com/conplementag/cops-hq/v2/pkg/recipes/azure_login"
+	"github.com/conplementag/cops-hq/v2/pkg/recipes/terraform"
+)
+
+// Process exit codes of a failed run (see ExitCode). The codes are stable, so that pipeline scripts can branch on them.
+const (
+	ExitCodeSuccess = 0
+
+	// ExitCodeError is used for all errors without a more specific exit code
+	ExitCodeError = 1
+
+	// ExitCodePanic is used for recovered panics which are not raised errors (PanicError), like runtime errors. Matches
+	// the exit code of a Go program crashing with a panic.
+	ExitCodePanic = 2
+
+	// ExitCodeCommandFailed is used if an executed command exited with a non-zero exit code (commands.ExitError)
+	ExitCodeCommandFailed = 3
+
+	// ExitCodeCommandTimeout is used if a command was killed because of a timeout or a cancellation
+	// (commands.TimeoutError)
+	ExitCodeCommandTimeout = 4
+
+	// ExitCodeCommandAborted is used if a command was killed because of an abort pattern (commands.AbortedError)
+	ExitCodeCommandAborted = 5
+
+	// ExitCodeToolingDependencies is used if a required tool is missing (exec.ErrNotFound) or outdated
+	// (ErrToolingDependencies)
+	ExitCodeToolingDependencies = 10
+
+	// ExitCodeNotLoggedIn is used if the Azure login failed (azure_login.ErrNotLoggedIn)
+	ExitCodeNotLoggedIn = 11
+
+	// ExitCodeNamingError is used for violations of the naming convention (naming.NamingError)
+	ExitCodeNamingError = 12
+
+	// ExitCodePlanNotApproved is used if the user declined to apply a terraform plan (terraform.ErrPlanNotApproved)
+	ExitCodePlanNotApproved = 20
+
+	// ExitCodeUserInputRequired is used if a prompt could not be answered, because the program runs non-interactively
+	// (commands.NonInteractiveError)
+	ExitCodeUserInputRequired = 21
+
+	// ExitCodeInterrupted is used if the commands were stopped because of SIGINT or SIGTERM (commands.ErrInterrupted).
+	// Matches the exit code of a shell script interrupted with Ctrl+C.
+	ExitCodeInterrupted = 130
+)
+
+// ExitCode returns the process exit code for the given error, ExitCodeSuccess for nil. Errors matching multiple exit
+// codes (like a failed login, which is caused by a failed az command) get the most specific one.
+func ExitCode(err error) int {
+	var nonInteractiveErr *commands.NonInteractiveError
+	var namingErr *naming.NamingError
+	var timeoutErr *commands.TimeoutError
+	var abortedErr *commands.AbortedError
+	var exitErr *commands.ExitError
+	var panicErr *PanicError
+
+	switch {
+	case err == nil:
+		return ExitCodeSuccess
+	case errors.Is(err, commands.ErrInterrupted):
+		return ExitCodeInterrupted
+	case errors.As(err, &panicErr):
+		return ExitCodePanic
+	case errors.Is(err, terraform.ErrPlanNotApproved):
+		return ExitCodePlanNotApproved
+	case errors.As(err, &nonInteractiveErr):
+		return ExitCodeUserInputRequired
+	case errors.Is(err, azure_login.ErrNotLoggedIn):
+		return ExitCodeNotLoggedIn
+	case errors.As(err, &namingErr):
+		return ExitCodeNamingError
+	case errors.Is(err, ErrToolingDependencies), errors.Is(err, exec.ErrNotFound):
+		return ExitCodeToolingDependencies
+	case errors.As(err, &timeoutErr):
+		return ExitCodeCommandTimeout
+	case errors.As(err, &abortedErr):
+		return ExitCodeCommandAborted
+	case errors.As(err, &exitErr):
+		return ExitCodeCommandFailed
+	default:
+		return ExitCodeError
+	}
+}
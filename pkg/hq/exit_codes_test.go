@@ -0,0 +1,183 @@
+package hq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/commands/commandstest"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
+	"github.com/conplementag/cops-hq/v2/pkg/naming"
+	"github.com/conplementag/cops-hq/v2/pkg/recipes/azure_login"
+	"github.com/conplementag/cops-hq/v2/pkg/recipes/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ErrorsAreMappedToExitCodes(t *testing.T) {
+	commandErr := &commands.ExitError{Command: "az login", ExitCode: 1, Cause: errors.New("exit status 1")}
+
+	tests := []struct {
+		name             string
+		err              error
+		expectedExitCode int
+	}{
+		{"no error", nil, ExitCodeSuccess},
+		{"unknown error", errors.New("something went wrong"), ExitCodeError},
+		{"failed command", commandErr, ExitCodeCommandFailed},
+		{"timeout", &commands.TimeoutError{Cause: context.DeadlineExceeded}, ExitCodeCommandTimeout},
+		{"aborted", &commands.AbortedError{}, ExitCodeCommandAborted},
+		{"missing tool", fmt.Errorf("exec: %w", exec.ErrNotFound), ExitCodeToolingDependencies},
+		{"outdated tool", fmt.Errorf("%w: %w", ErrToolingDependencies, &ToolVersionError{Tool: "helm"}),
+			ExitCodeToolingDependencies},
+		{"failed login", &azure_login.LoginError{Method: "azure service principal", Cause: commandErr},
+			ExitCodeNotLoggedIn},
+		{"naming", naming.NewNamingError("region must be provided"), ExitCodeNamingError},
+		{"declined plan", fmt.Errorf("deployment failed: %w", terraform.ErrPlanNotApproved), ExitCodePlanNotApproved},
+		{"non-interactive", &commands.NonInteractiveError{Prompt: "Apply?"}, ExitCodeUserInputRequired},
+		{"panic", &PanicError{Value: "boom"}, ExitCodePanic},
+		{"interrupted", fmt.Errorf("%w: %w", commands.ErrInterrupted, commandErr), ExitCodeInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedExitCode, ExitCode(tt.err))
+		})
+	}
+}
+
+func Test_RunEndsWithExitCodeOfErrorInPanicMode(t *testing.T) {
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{Quiet: true, DisableFileLogging: true, DisableRunSummary: true})
+	assert.NoError(t, err)
+
+	exitCode := -1
+	previousExit := exit
+	exit = func(code int) { exitCode = code }
+	error_handling.PanicOnAnyError = true
+
+	t.Cleanup(func() {
+		exit = previousExit
+		error_handling.PanicOnAnyError = false
+	})
+
+	hq.GetCli().AddBaseCommand("deploy", "", "", func() {
+		hq.GetExecutor().Execute("go no-such-go-command")
+	})
+	hq.GetCli().GetRootCommand().SetArgs([]string{"deploy"})
+
+	// Act
+	err = hq.Run()
+
+	// Assert
+	var exitErr *commands.ExitError
+	assert.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, ExitCodeCommandFailed, exitCode)
+}
+
+func Test_FailedLoginInPanicModeIsMappedToNotLoggedIn(t *testing.T) {
+	fake := commandstest.NewFakeExecutor()
+	fake.Policy = error_handling.Panic
+	fake.OnPrefix("az login").Returns("").WithExitCode(1)
+
+	azureLogin := azure_login.NewWithParams(fake, "sp-client-id", "sp-client-secret", "sp-tenantId", "", "", false)
+
+	// Act
+	var recovered any
+	func() {
+		defer func() { recovered = recover() }()
+		azureLogin.Login()
+	}()
+
+	// Assert
+	err, isError := recovered.(error)
+	if assert.True(t, isError, "the failed login panics with an error") {
+		assert.Equal(t, ExitCodeNotLoggedIn, ExitCode(err))
+	}
+}
+
+func Test_RunFollowsErrorPolicyOfInstance(t *testing.T) {
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{Quiet: true, DisableFileLogging: true, DisableRunSummary: true,
+		ErrorPolicy: error_handling.Panic})
+	assert.NoError(t, err)
+
+	exitCode := -1
+	previousExit := exit
+	exit = func(code int) { exitCode = code }
+	t.Cleanup(func() { exit = previousExit })
+
+	hq.GetCli().AddBaseCommand("deploy", "", "", func() {
+		// expected failures do not end the run
+		hq.GetExecutor().Try().Execute("go no-such-go-command")
+		hq.GetExecutor().Execute("go no-such-go-command")
+	})
+	hq.GetCli().GetRootCommand().SetArgs([]string{"deploy"})
+
+	// Act
+	err = hq.Run()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, ExitCodeCommandFailed, exitCode)
+}
+
+func Test_RunAndExitEndsWithExitCodeOfErrorInReturnMode(t *testing.T) {
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{Quiet: true, DisableFileLogging: true, DisableRunSummary: true,
+		ErrorPolicy: error_handling.Return})
+	assert.NoError(t, err)
+
+	exitCode := -1
+	previousExit := exit
+	exit = func(code int) { exitCode = code }
+	t.Cleanup(func() { exit = previousExit })
+
+	hq.GetCli().AddBaseCommand("deploy", "", "", func() {})
+
+	// Act
+	hq.GetCli().GetRootCommand().SetArgs([]string{"deploy"})
+	hq.RunAndExit()
+	exitCodeOfSuccessfulRun := exitCode
+
+	hq.GetCli().GetRootCommand().SetArgs([]string{"no-such-command"})
+	hq.RunAndExit()
+
+	// Assert
+	assert.Equal(t, -1, exitCodeOfSuccessfulRun)
+	assert.Equal(t, ExitCodeError, exitCode)
+}
+
+func Test_RunRecoversPanicsAndCallsShutdownHooks(t *testing.T) {
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{Quiet: true, DisableFileLogging: true, DisableRunSummary: true})
+	assert.NoError(t, err)
+
+	exitCode := -1
+	previousExit := exit
+	exit = func(code int) { exitCode = code }
+	t.Cleanup(func() { exit = previousExit })
+
+	var calledHooks []string
+	hq.OnShutdown(func() { calledHooks = append(calledHooks, "first") })
+	hq.OnShutdown(func() { panic("failing hook") })
+	hq.OnShutdown(func() { calledHooks = append(calledHooks, "last") })
+
+	hq.GetCli().AddBaseCommand("deploy", "", "", func() {
+		var settings map[string]string
+		settings["region"] = "westeurope"
+	})
+	hq.GetCli().GetRootCommand().SetArgs([]string{"deploy"})
+
+	// Act
+	err = hq.Run()
+
+	// Assert
+	var panicErr *PanicError
+	var runtimeErr runtime.Error
+	if assert.ErrorAs(t, err, &panicErr) {
+		assert.NotEmpty(t, panicErr.Stack)
+	}
+	assert.ErrorAs(t, err, &runtimeErr)
+	assert.Equal(t, ExitCodePanic, exitCode)
+	assert.Equal(t, []string{"last", "first"}, calledHooks)
+}
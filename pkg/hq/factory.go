@@ -1,9 +1,15 @@
 package hq
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/conplementag/cops-hq/v2/internal"
+	"github.com/conplementag/cops-hq/v2/internal/summary"
 	"github.com/conplementag/cops-hq/v2/pkg/cli"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
 	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/sirupsen/logrus"
 )
 
 // New creates a new HQ instance, configuring internally used modules for usage. Keep the created HQ instance and
@@ -11,7 +17,7 @@ import (
 // It will create a chatty executor, piping all commands and outputs to both the console and the file (e.g. like
 // in shell scripts)
 func New(programName string, version string, logFileName string) HQ {
-	return create(programName, version, &HqOptions{
+	return mustCreate(programName, version, &HqOptions{
 		Quiet:       false,
 		LogFileName: logFileName,
 	})
@@ -22,7 +28,7 @@ func New(programName string, version string, logFileName string) HQ {
 // Quiet HQ will create a quiet executor, piping all commands and outputs to the log file, but the console will be
 // kept clean. If needed, like in CI, a viper flag "verbose" can be used to override this behavior.
 func NewQuiet(programName string, version string, logFileName string) HQ {
-	return create(programName, version, &HqOptions{
+	return mustCreate(programName, version, &HqOptions{
 		Quiet:       true,
 		LogFileName: logFileName,
 	})
@@ -30,29 +36,101 @@ func NewQuiet(programName string, version string, logFileName string) HQ {
 
 // NewCustom creates a new HQ instance, configuring internally used modules for usage. Keep the created HQ instance and
 // avoid re-instantiation since some setup steps might have global impacts (like logging setup).
-// Custom HQ lets you override most of the behaviours and HQ setup options
-func NewCustom(programName string, version string, options *HqOptions) HQ {
-	return create(programName, version, options)
+// Custom HQ lets you override most of the behaviours and HQ setup options. Invalid options (see HqOptions.Validate) are
+// returned as error.
+func NewCustom(programName string, version string, options *HqOptions) (HQ, error) {
+	hq, err := create(programName, version, options)
+	if err != nil {
+		return nil, internal.ReturnErrorOrPanicWith(options.ErrorPolicy, err)
+	}
+
+	return hq, nil
+}
+
+// mustCreate is used by the factories without options, which can only fail if the log file cannot be initialized
+func mustCreate(programName string, version string, options *HqOptions) HQ {
+	hq, err := create(programName, version, options)
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	return hq
 }
 
-func create(programName string, version string, options *HqOptions) HQ {
-	logger := logging.Init(options.LogFileName)
+func create(programName string, version string, options *HqOptions) (HQ, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	if options.LogDirectory != "" {
+		if err := os.MkdirAll(options.LogDirectory, 0755); err != nil {
+			return nil, fmt.Errorf("could not create the log directory %s: %w", options.LogDirectory, err)
+		}
+	}
+
+	loggingOptions := options.loggingOptions()
+	loggingOptions.ProgramName = programName
+	loggingOptions.Version = version
+
+	logger, err := logging.InitCustom(loggingOptions)
+	if err != nil {
+		return nil, err
+	}
+
 	cli := cli.New(programName, version)
 
-	var exec commands.Executor
+	// the executor skips the log file sink, if no log file is given
+	logFileName := loggingOptions.LogFileName
+	if options.DisableFileLogging {
+		logFileName = ""
+	}
+
+	auditFileName := options.inLogDirectory(options.AuditFileName)
 
-	if options.Quiet {
-		exec = commands.NewQuiet(options.LogFileName, logger)
+	middlewares := options.Middlewares
+	if !options.DisableRunSummary {
+		summary.Start(programName, version, logging.RunId())
+		middlewares = append([]commands.Middleware{runSummaryMiddleware()}, middlewares...)
 	} else {
-		exec = commands.NewChatty(options.LogFileName, logger)
+		summary.Stop()
 	}
 
+	if auditFileName != "" {
+		// outermost middleware, so that the audited result is the one returned to the caller
+		middlewares = append([]commands.Middleware{commands.NewAuditMiddleware(auditFileName, programName, version)},
+			middlewares...)
+	}
+
+	exec := commands.NewCustom(logFileName, logger, &commands.ExecutorOptions{
+		Chatty:               !options.Quiet,
+		DefaultTimeout:       options.CommandTimeout,
+		StrictCommandParsing: options.StrictCommandParsing,
+		Middlewares:          middlewares,
+		ErrorPolicy:          options.ErrorPolicy,
+	})
+
 	container := &hqContainer{
-		Executor: exec,
-		Cli:      cli,
-		Logger:   logger,
+		Executor:      exec,
+		Cli:           cli,
+		Logger:        logger,
+		AuditFileName: auditFileName,
+
+		ShowRunSummary:             !options.DisableRunSummary && options.ConsoleFormat != logging.FormatJSON,
+		RunSummaryMarkdownFileName: options.inLogDirectory(options.RunSummaryMarkdownFileName),
+		RunSummaryJsonFileName:     options.inLogDirectory(options.RunSummaryJsonFileName),
+
+		ShutdownGracePeriod: options.ShutdownGracePeriod,
 	}
 
+	if container.ShutdownGracePeriod == 0 {
+		container.ShutdownGracePeriod = DefaultShutdownGracePeriod
+	}
+
+	// registered first, so that the buffered log file writes are flushed after all other hooks (also if the program is
+	// ended by a shutdown signal)
+	container.OnShutdown(flushLogFiles)
+
 	addInbuiltHqCliCommands(cli, container)
-	return container
+	return container, nil
 }
@@ -1,7 +1,14 @@
 package hq
 
 import (
+	internalLogging "github.com/conplementag/cops-hq/v2/internal/logging"
+	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -10,3 +17,46 @@ func Test_NewWillCreateFunctioningHQInstance(t *testing.T) {
 	assert.NotNil(t, hq.GetCli())
 	assert.NotNil(t, hq.GetExecutor())
 }
+
+func Test_NewCustomReturnsValidationErrors(t *testing.T) {
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{LogLevel: "debug"})
+	assert.Error(t, err)
+	assert.Nil(t, hq)
+}
+
+func Test_NewCustomWritesLogFileToLogDirectory(t *testing.T) {
+	logDirectory := filepath.Join(t.TempDir(), "logs")
+	testMessage := uuid.New().String()
+
+	// the log file is kept open, and needs to be closed before the directory is removed
+	t.Cleanup(func() {
+		logging.InitCustom(&logging.Options{DisableFileLogging: true})
+		internalLogging.SharedLogFile(filepath.Join(logDirectory, "test-logs.txt")).Reopen()
+	})
+
+	// Act
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{LogFileName: "test-logs.txt", LogDirectory: logDirectory})
+	logrus.Info(testMessage)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotNil(t, hq.GetExecutor())
+	testing_utils.CheckFileContainsString(t, filepath.Join(logDirectory, "test-logs.txt"), testMessage)
+}
+
+func Test_NewCustomWithoutFileLoggingWritesNoFiles(t *testing.T) {
+	workingDirectory := t.TempDir()
+	t.Chdir(workingDirectory)
+
+	// Act
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{DisableFileLogging: true, Quiet: true})
+	assert.NoError(t, err)
+
+	_, err = hq.GetExecutor().Execute("go version")
+
+	// Assert
+	assert.NoError(t, err)
+
+	files, _ := os.ReadDir(workingDirectory)
+	assert.Empty(t, files)
+}
@@ -10,9 +10,26 @@ import (
 // setting up (e.g. all CLI commands added to HQ.Cli). Consider this object similar to an IoC container, which can be
 // used to retrieve main dependencies for other objects, such as the command executor or the CLI.
 type HQ interface {
-	// Run starts the HQ CLI parsing functionality
+	// Run starts the HQ CLI parsing functionality. In panic mode (see HqOptions.ErrorPolicy), a failed run ends
+	// the program with the exit code matching the error (see ExitCode), instead of a panic. Otherwise, the error is
+	// returned, and can be mapped to the exit code with ExitCode (or use RunAndExit). Other panics (like runtime errors) are recovered as
+	// well, and end the program with ExitCodePanic.
+	// While running, SIGINT and SIGTERM are forwarded to the running commands, which are killed if still running after
+	// HqOptions.ShutdownGracePeriod (see commands.HandleShutdownSignals). If no command is running (e.g. while waiting
+	// for a prompt), or on a second signal, the program ends with ExitCodeInterrupted. The hooks registered with
+	// OnShutdown are called at the end in any case.
 	Run() error
 
+	// RunAndExit is same as Run, but a failed run always ends the program with the exit code matching the error (see
+	// ExitCode), in every error policy. Use it as the last call in main, so that CI systems can react on the exit code.
+	RunAndExit()
+
+	// OnShutdown registers a cleanup function, which is called at the end of Run: after a successful or failed run, a
+	// recovered panic, or after the running commands were stopped because of SIGINT or SIGTERM. Hooks are called in
+	// reverse order of registration (like defer), e.g. to remove temporary files containing secrets, like the helm
+	// values.override.yaml or terraform tfvars files.
+	OnShutdown(hook func())
+
 	// GetExecutor retrieves the currently configured executor
 	GetExecutor() commands.Executor
 
@@ -37,6 +54,11 @@ type HQ interface {
 	// a procondition due to get raw configuration string
 	GetRawConfigurationFile() (string, error)
 
+	// RegisterSecret registers a secret value (like a password or an access key), which will be masked with *** in
+	// all logs, command output and error messages. See commands.Executor RegisterSecret for details. Recipes register
+	// the secrets they handle (like login credentials or storage account keys) on their own.
+	RegisterSecret(value string)
+
 	// CheckToolingDependencies can be called to check if installed tooling (Azure CLI, Terraform, Helm etc.) is of minimal
 	// expected version for all of HQ functionality to work. It is highly recommended to call this method in your code, and fail
 	// in case of errors.
@@ -1,8 +1,12 @@
 package hq
 
 import (
+	"os"
+	"time"
+
 	"github.com/conplementag/cops-hq/v2/pkg/cli"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 func addInbuiltHqCliCommands(cli cli.Cli, container *hqContainer) {
@@ -18,4 +22,41 @@ func addInbuiltHqCliCommands(cli cli.Cli, container *hqContainer) {
 				panic(err)
 			}
 		})
+
+	auditCommand := hqBaseCommand.AddCommand("audit", "Audit trail of the executed commands",
+		"Commands to inspect the audit trail, which is written if HqOptions.AuditFileName is set.", nil)
+
+	showAuditCommand := auditCommand.AddCommand("show", "Lists the executed commands from the audit file",
+		"Use this command to list the commands recorded in the audit file, optionally filtered by the command text, "+
+			"the user, the time or the result.", func() {
+			filter := auditFilter{
+				contains:   viper.GetString("contains"),
+				user:       viper.GetString("by-user"),
+				failedOnly: viper.GetBool("failed-only"),
+			}
+
+			var err error
+			if since := viper.GetString("since"); since != "" {
+				filter.since, err = time.ParseDuration(since)
+			}
+
+			if err == nil {
+				err = showAuditEntries(viper.GetString("audit-file"), filter, viper.GetBool("output-json"), os.Stdout)
+			}
+
+			if err != nil {
+				logrus.Error(err)
+				panic(err)
+			}
+		})
+
+	showAuditCommand.AddParameterString("audit-file", container.AuditFileName, container.AuditFileName == "", "f",
+		"Audit file to read, defaults to the audit file configured for this program")
+	showAuditCommand.AddParameterString("contains", "", false, "c", "Only show commands containing the given text")
+	showAuditCommand.AddParameterString("by-user", "", false, "u", "Only show commands executed by the given user")
+	showAuditCommand.AddParameterString("since", "", false, "s", "Only show commands executed within the given "+
+		"duration, e.g. 24h or 30m")
+	showAuditCommand.AddParameterBool("failed-only", false, false, "", "Only show commands with a non-zero exit code")
+	showAuditCommand.AddParameterBool("output-json", false, false, "", "Show the entries as JSON lines, e.g. for "+
+		"further processing with jq")
 }
@@ -1,6 +1,14 @@
 package hq
 
-import "errors"
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+)
 
 type HqOptions struct {
 	// Quiet HQ will create a quiet executor, piping all commands and outputs to the log file, but the console will be
@@ -10,14 +18,111 @@ type HqOptions struct {
 	// Filename for the log file, if enabled
 	LogFileName string
 
-	// Default logging to file can be disabled with this flag
+	// Default logging to file can be disabled with this flag. Logs and command output are then only written to the
+	// console (command output only for chatty HQ, or with the viper flag "verbose").
 	DisableFileLogging bool
+
+	// LogDirectory is the directory for the log file and the audit file, if they are given with relative file names.
+	// The directory is created if needed. Useful for containers with a read-only filesystem, where only some
+	// directories are writable. Empty (default) means the current working directory.
+	LogDirectory string
+
+	// LogLevel is the minimal level of the logged entries (e.g. "debug" or "warning"). Empty (default) means
+	// logging.DefaultLogLevel.
+	LogLevel string
+
+	// ConsoleFormat of the console log output (e.g. logging.FormatPlainText or logging.FormatJSON). Empty (default) means
+	// logging.FormatText.
+	ConsoleFormat logging.Format
+
+	// DisableCIIntegration turns off the collapsible sections and annotations in Azure DevOps and GitHub Actions (see
+	// logging.StartGroup)
+	DisableCIIntegration bool
+
+	// FileFormat of the log file (e.g. logging.FormatJSON for log aggregation). Empty (default) means the same plain
+	// text format as on the console, without colors.
+	FileFormat logging.Format
+
+	// CommandTimeout is the default timeout for every command run by the executor. Commands running longer are killed.
+	// Zero (default) means no timeout.
+	CommandTimeout time.Duration
+
+	// StrictCommandParsing lets the executor reject ambiguous command strings, instead of executing them with a best
+	// guess. Check commands.TokenizeStrict for details.
+	StrictCommandParsing bool
+
+	// Middlewares wrap every command executed by the HQ executor, including the commands of all recipes. Check
+	// commands.Middleware for details.
+	Middlewares []commands.Middleware
+
+	// AuditFileName enables the audit trail, if set. One JSON line is appended to the audit file for every executed
+	// command (see commands.NewAuditMiddleware). The entries can be listed with the in-built 'hq audit show' command.
+	AuditFileName string
+
+	// DisableRunSummary turns off the run summary, which is otherwise shown on the console at the end of Run(). The
+	// summary covers the executed commands, the durations of the recipe steps, the terraform changes, the deployed helm
+	// releases, the logged warnings and the final error. It is not shown with logging.FormatJSON console output, use
+	// RunSummaryJsonFileName instead.
+	DisableRunSummary bool
+
+	// RunSummaryMarkdownFileName is the file the run summary is appended to as Markdown, if set. Can be attached to the
+	// pipeline run, e.g. with os.Getenv("GITHUB_STEP_SUMMARY") in GitHub Actions.
+	RunSummaryMarkdownFileName string
+
+	// RunSummaryJsonFileName is the file the run summary is written to as JSON, if set
+	RunSummaryJsonFileName string
+
+	// ErrorPolicy defines whether the errors of this HQ instance (its executor, and the recipes using it) are returned
+	// or raised as panic. Default follows the global error_handling.PanicOnAnyError flag. Check error_handling.Policy
+	// for details.
+	ErrorPolicy error_handling.Policy
+
+	// ShutdownGracePeriod is the time the running commands get to end gracefully, after SIGINT or SIGTERM was received
+	// during Run (e.g. for terraform to release its state lock). Commands still running afterwards are killed. Zero
+	// (default) means DefaultShutdownGracePeriod.
+	ShutdownGracePeriod time.Duration
 }
 
+// DefaultShutdownGracePeriod is the default of HqOptions.ShutdownGracePeriod
+const DefaultShutdownGracePeriod = 30 * time.Second
+
 func (options *HqOptions) Validate() error {
 	if options.LogFileName == "" && !options.DisableFileLogging {
 		return errors.New("you need to define the logFileName if logging to the file is enabled")
 	}
 
-	return nil
+	if options.CommandTimeout < 0 {
+		return errors.New("the command timeout cannot be negative")
+	}
+
+	if options.ShutdownGracePeriod < 0 {
+		return errors.New("the shutdown grace period cannot be negative")
+	}
+
+	if options.DisableRunSummary && (options.RunSummaryMarkdownFileName != "" || options.RunSummaryJsonFileName != "") {
+		return errors.New("the run summary files cannot be written if the run summary is disabled")
+	}
+
+	return options.loggingOptions().Validate()
+}
+
+func (options *HqOptions) loggingOptions() *logging.Options {
+	return &logging.Options{
+		LogFileName:        options.inLogDirectory(options.LogFileName),
+		DisableFileLogging: options.DisableFileLogging,
+		Level:              options.LogLevel,
+		ConsoleFormat:      options.ConsoleFormat,
+		FileFormat:         options.FileFormat,
+
+		DisableCIIntegration: options.DisableCIIntegration,
+	}
+}
+
+// inLogDirectory returns the path of the given file in the log directory, if the file name is relative
+func (options *HqOptions) inLogDirectory(fileName string) string {
+	if fileName == "" || options.LogDirectory == "" || filepath.IsAbs(fileName) {
+		return fileName
+	}
+
+	return filepath.Join(options.LogDirectory, fileName)
 }
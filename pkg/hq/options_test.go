@@ -1,8 +1,11 @@
 package hq
 
 import (
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
 	"github.com/stretchr/testify/assert"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func Test_LogFileNameNotRequiredWhenLoggingToFileDisabled(t *testing.T) {
@@ -17,3 +20,24 @@ func Test_LogFileNameRequired(t *testing.T) {
 	optionsWithFileName := &HqOptions{DisableFileLogging: false, LogFileName: "bla.log"}
 	assert.NoError(t, optionsWithFileName.Validate())
 }
+
+func Test_InvalidLoggingOptionsAreRejected(t *testing.T) {
+	assert.Error(t, (&HqOptions{LogFileName: "bla.log", LogLevel: "chatty"}).Validate())
+	assert.Error(t, (&HqOptions{LogFileName: "bla.log", ConsoleFormat: "xml"}).Validate())
+	assert.Error(t, (&HqOptions{LogFileName: "bla.log", CommandTimeout: -time.Second}).Validate())
+	assert.Error(t, (&HqOptions{LogFileName: "bla.log", ShutdownGracePeriod: -time.Second}).Validate())
+	assert.Error(t, (&HqOptions{LogFileName: "bla.log", DisableRunSummary: true, RunSummaryJsonFileName: "run.json"}).Validate())
+
+	valid := &HqOptions{LogFileName: "bla.log", LogLevel: "debug", ConsoleFormat: logging.FormatPlainText}
+	assert.NoError(t, valid.Validate())
+}
+
+func Test_RelativeFileNamesArePlacedInLogDirectory(t *testing.T) {
+	options := &HqOptions{LogDirectory: "logs"}
+
+	assert.Equal(t, filepath.Join("logs", "app.log"), options.inLogDirectory("app.log"))
+	assert.Equal(t, "", options.inLogDirectory(""))
+
+	absolute, _ := filepath.Abs("app.log")
+	assert.Equal(t, absolute, options.inLogDirectory(absolute))
+}
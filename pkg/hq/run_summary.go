@@ -0,0 +1,72 @@
+package hq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/conplementag/cops-hq/v2/internal/summary"
+	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// runSummaryMiddleware records every executed command in the run summary
+func runSummaryMiddleware() commands.Middleware {
+	return commands.MiddlewareFuncs{
+		AfterFunc: func(ctx context.Context, cmd *exec.Cmd, result *commands.Result, err error) (*commands.Result, error) {
+			summary.RecordCommand(result.Command, result.ExitCode, result.Duration)
+			return result, err
+		},
+	}
+}
+
+// reportRunSummary shows the run summary on the console, and writes the summary files, if configured. Since the run is
+// already finished, failures to write the files are only logged as errors.
+func (hq *hqContainer) reportRunSummary(runErr error) {
+	runSummary := summary.Finish(logging.CurrentCliCommand(), runErr)
+	if runSummary == nil || runSummary.IsEmpty() {
+		return
+	}
+
+	if hq.ShowRunSummary {
+		fmt.Println()
+		runSummary.WriteTable(os.Stdout)
+	}
+
+	if hq.RunSummaryMarkdownFileName != "" {
+		if err := appendRunSummaryMarkdown(runSummary, hq.RunSummaryMarkdownFileName); err != nil {
+			logrus.Errorf("could not write the run summary to %s: %v", hq.RunSummaryMarkdownFileName, err)
+		}
+	}
+
+	if hq.RunSummaryJsonFileName != "" {
+		if err := writeRunSummaryJson(runSummary, hq.RunSummaryJsonFileName); err != nil {
+			logrus.Errorf("could not write the run summary to %s: %v", hq.RunSummaryJsonFileName, err)
+		}
+	}
+}
+
+// appendRunSummaryMarkdown appends the summary, so that files shared by multiple runs (like the GitHub step summary)
+// keep the summaries of the previous runs
+func appendRunSummaryMarkdown(runSummary *summary.Summary, fileName string) error {
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return runSummary.WriteMarkdown(file)
+}
+
+func writeRunSummaryJson(runSummary *summary.Summary, fileName string) error {
+	content, err := json.MarshalIndent(runSummary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, append(content, '\n'), 0644)
+}
@@ -0,0 +1,52 @@
+package hq
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conplementag/cops-hq/v2/internal/summary"
+	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RunWritesRunSummaryFiles(t *testing.T) {
+	summaryDirectory := t.TempDir()
+	markdownFileName := filepath.Join(summaryDirectory, "summary.md")
+	os.WriteFile(markdownFileName, []byte("previous step\n"), 0644)
+
+	hq, err := NewCustom("hq", "0.0.1", &HqOptions{
+		Quiet:                      true,
+		DisableFileLogging:         true,
+		RunSummaryMarkdownFileName: markdownFileName,
+		RunSummaryJsonFileName:     filepath.Join(summaryDirectory, "summary.json"),
+	})
+	assert.NoError(t, err)
+	t.Cleanup(summary.Stop)
+
+	hq.GetCli().AddBaseCommand("deploy", "", "", func() {
+		hq.GetExecutor().Execute("go version")
+		logrus.Warn("sops is outdated")
+	})
+	hq.GetCli().GetRootCommand().SetArgs([]string{"deploy"})
+
+	// Act
+	err = hq.Run()
+
+	// Assert
+	assert.NoError(t, err)
+
+	content, _ := os.ReadFile(filepath.Join(summaryDirectory, "summary.json"))
+	var runSummary summary.Summary
+	assert.NoError(t, json.Unmarshal(content, &runSummary))
+	assert.Equal(t, "hq deploy", runSummary.CliCommand)
+	assert.Equal(t, []string{"sops is outdated"}, runSummary.Warnings)
+	if assert.Len(t, runSummary.Commands, 1) {
+		assert.Equal(t, "go version", runSummary.Commands[0].Command)
+	}
+
+	testing_utils.CheckFileContainsString(t, markdownFileName, "previous step\n## Run summary of hq deploy: succeeded")
+	testing_utils.CheckFileContainsString(t, markdownFileName, "| `go version` | 0 |")
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"github.com/conplementag/cops-hq/v2/internal/ci"
+)
+
+// CISystem is a CI system with native support for collapsible log sections and annotations, which is detected and
+// used automatically by the logging initialization (see Options.DisableCIIntegration)
+type CISystem = ci.System
+
+const (
+	CINone          = ci.None
+	CIAzureDevOps   = ci.AzureDevOps
+	CIGitHubActions = ci.GitHubActions
+)
+
+// ActiveCISystem returns the CI system the console output is integrated with, CINone if there is none
+func ActiveCISystem() CISystem {
+	return ci.Active()
+}
+
+// StartGroup starts a collapsible section of the console output, if running in a supported CI system. Call the returned
+// function to end the section. Sections cannot be nested, a section started within another section is ignored. Every
+// command executed by the executor, and the phases of the recipes (like "Terraform init") are grouped automatically.
+func StartGroup(title string) (end func()) {
+	return ci.StartGroup(title)
+}
+
+// AnnotateError shows the error as error annotation of the CI run, if running in a supported CI system. Errors returned
+// by cops-hq are annotated automatically.
+func AnnotateError(err error) {
+	ci.AnnotateError(err)
+}
+
+// AnnotateWarning shows the message as warning annotation of the CI run, if running in a supported CI system
+func AnnotateWarning(message string) {
+	ci.AnnotateWarning(message)
+}
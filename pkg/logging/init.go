@@ -2,9 +2,11 @@ package logging
 
 import (
 	"fmt"
+	"github.com/conplementag/cops-hq/v2/internal/ci"
+	"github.com/conplementag/cops-hq/v2/internal/logging"
 	"github.com/sirupsen/logrus"
-	"github.com/snowzach/rotatefilehook"
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+	"slices"
 	"time"
 
 	"github.com/mattn/go-colorable"
@@ -12,6 +14,11 @@ import (
 
 var DefaultLogLevel = logrus.InfoLevel
 
+func init() {
+	// logrus.Fatal ends the program right away, the buffered log file writes are written out before
+	logrus.RegisterExitHandler(func() { logging.FlushLogFiles() })
+}
+
 // Init will initialize the Logrus system, which per default sets up logging to
 // console and to file at the same time. Features are file rotation,
 // fixed colors on Windows etc.
@@ -20,24 +27,79 @@ var DefaultLogLevel = logrus.InfoLevel
 // so that if needed, it can be still used for dependency injection. Still, using logrus.Info()
 // and other methods directly is not evil.
 func Init(logFileName string) *logrus.Logger {
-	var logLevel = DefaultLogLevel
+	logger, err := InitCustom(&Options{LogFileName: logFileName})
+
+	if err != nil {
+		logrus.Error(err)
+		panic(err)
+	}
+
+	return logger
+}
 
-	// main reason we use the prefixed library TextFormatter, instead of the default logrus.TextFormatter,
-	// it to have the "ForceFormatting" option, which enables the same format in all TTY and non-TTY
-	// execution environments.
-	consoleFormatter := &prefixed.TextFormatter{
+// Flush writes out the buffered log entries and command output to the log file, which are otherwise written once the
+// buffer is full or after a second. HQ flushes at the end of hq.Run, programs using the logging without HQ should call
+// Flush before they end (e.g. deferred in main).
+func Flush() error {
+	return logging.FlushLogFiles()
+}
+
+// InitCustom is same as Init, except the logging is configured via the given options (e.g. console only logging, or a
+// different log level). Check the Options for details. Invalid options are returned as error.
+func InitCustom(options *Options) (*logrus.Logger, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	logLevel := options.level()
+	setProgram(options.ProgramName, options.Version)
+
+	structuredFormatter := newJsonFormatter()
+
+	var consoleFormatter logrus.Formatter = &prefixed.TextFormatter{
 		// required to show colors in build for example, which would otherwise
 		// show plain output because build is not a TTY session
-		ForceColors:     true,
+		ForceColors:     options.ConsoleFormat != FormatPlainText,
+		DisableColors:   options.ConsoleFormat == FormatPlainText,
 		FullTimestamp:   true,
 		TimestampFormat: time.RFC822,
 		// make sure the format is kept in all execution envs
 		ForceFormatting: true,
 	}
 
-	fileFormatter := &prefixed.TextFormatter{
+	// command output is shown on the console as it is, unless the entries are written as JSON
+	var consoleOutputFormatter logging.OutputFormatter
+
+	if options.ConsoleFormat == FormatJSON {
+		consoleFormatter = structuredFormatter
+		consoleOutputFormatter = structuredFormatter.formatOutput
+	}
+
+	logging.SetConsoleOutputFormatter(consoleOutputFormatter)
+
+	if options.DisableCIIntegration {
+		ci.Activate(ci.None)
+	} else {
+		ci.Activate(ci.Detect())
+	}
+
+	logrus.SetLevel(logLevel)
+
+	// special stdout io.Writer capable of colors on Windows
+	logrus.SetOutput(colorable.NewColorableStdout())
+	logrus.SetFormatter(consoleFormatter)
+
+	// secrets need to be masked before any of the other hooks writes the entry
+	hooks := []logrus.Hook{&redactionHook{}, &summaryHook{}}
+
+	if options.DisableFileLogging {
+		replaceInstalledHooks(hooks)
+		return logrus.StandardLogger(), nil
+	}
+
+	var fileFormatter logrus.Formatter = &prefixed.TextFormatter{
 		// files will show colors in raw format, which pollutes the files too much.
-		// Problematic output look like: "[36mINFO[0m", which we don't want.
+		// Problematic output look like: "[36mINFO[0m", which we don't want.
 		// Colors are only available as console output.
 		DisableColors:   true,
 		FullTimestamp:   true,
@@ -46,29 +108,50 @@ func Init(logFileName string) *logrus.Logger {
 		ForceFormatting: true,
 	}
 
-	rotateFileHook, err := rotatefilehook.NewRotateFileHook(rotatefilehook.RotateFileConfig{
-		Filename:   logFileName,
-		MaxSize:    50, // megabytes
-		MaxBackups: 3,
-		MaxAge:     90, // days
-		Level:      logLevel,
-		Formatter:  fileFormatter,
-	})
+	// the log file is shared with the command output of the executor, and rotated once it gets too large. It is
+	// reopened in case the file was moved or deleted since the last initialization.
+	logFile := logging.SharedLogFile(options.LogFileName)
 
-	if err != nil {
-		err := fmt.Errorf("failed to initialize file rotate hook: %v", err)
-		logrus.Error(err)
-		panic(err)
+	if err := logFile.Reopen(); err != nil {
+		return nil, fmt.Errorf("failed to initialize the log file: %v", err)
 	}
 
-	logrus.SetLevel(logLevel)
+	logFile.SetOutputFormatter(nil)
 
-	// special stdout io.Writer capable of colors on Windows
-	logrus.SetOutput(colorable.NewColorableStdout())
-	logrus.SetFormatter(consoleFormatter)
+	if options.FileFormat == FormatJSON {
+		fileFormatter = structuredFormatter
+		logFile.SetOutputFormatter(structuredFormatter.formatOutput)
+	}
 
 	// this hook will also route logs to file
-	logrus.AddHook(rotateFileHook)
+	hooks = append(hooks, &logFileHook{logFile: logFile, formatter: fileFormatter, level: logLevel})
+	replaceInstalledHooks(hooks)
+
+	return logrus.StandardLogger(), nil
+}
+
+// installedHooks are the hooks added by the last initialization
+var installedHooks []logrus.Hook
+
+// replaceInstalledHooks adds the given hooks, and removes the hooks of a previous initialization, so that a
+// re-initialization does not write the entries twice, or to the previous log file. Hooks added by other code are kept.
+func replaceInstalledHooks(hooks []logrus.Hook) {
+	logger := logrus.StandardLogger()
+	replaced := make(logrus.LevelHooks)
+
+	// hooks added by other code are called after the redaction, so that they do not see any secrets either
+	for _, hook := range hooks {
+		replaced.Add(hook)
+	}
+
+	for level, levelHooks := range logger.Hooks {
+		for _, hook := range levelHooks {
+			if !slices.Contains(installedHooks, hook) {
+				replaced[level] = append(replaced[level], hook)
+			}
+		}
+	}
 
-	return logrus.StandardLogger()
+	logger.ReplaceHooks(replaced)
+	installedHooks = hooks
 }
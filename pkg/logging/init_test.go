@@ -1,10 +1,15 @@
 package logging
 
 import (
+	"encoding/json"
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
 	"github.com/conplementag/cops-hq/v2/internal/testing_utils"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -22,3 +27,82 @@ func Test_WriteToBothFileAndConsole(t *testing.T) {
 	testing_utils.CheckFileContainsString(t, logFile, testMessage)
 	os.Remove(logFile)
 }
+
+func Test_RegisteredSecretsAreMaskedInLogs(t *testing.T) {
+	// Arrange
+	logFile := "test_file.log"
+	secret := uuid.New().String()
+	secrets.Register(secret)
+
+	// Act
+	Init(logFile)
+	logrus.Info("the secret is " + secret)
+
+	// Assert
+	testing_utils.CheckFileContainsString(t, logFile, "the secret is ***")
+	testing_utils.CheckFileDoesNotContainString(t, logFile, secret)
+	os.Remove(logFile)
+}
+
+func Test_ReinitializationDoesNotDuplicateEntries(t *testing.T) {
+	// Arrange
+	logFile := "test_file.log"
+	testMessage := uuid.New().String()
+
+	// Act
+	Init(logFile)
+	Init(logFile)
+	logrus.Info(testMessage)
+
+	// Assert
+	assert.NoError(t, Flush())
+	content, _ := os.ReadFile(logFile)
+	assert.Equal(t, 1, strings.Count(string(content), testMessage))
+	os.Remove(logFile)
+}
+
+func Test_InvalidOptionsAreRejected(t *testing.T) {
+	_, err := InitCustom(&Options{LogFileName: "test_file.log", Level: "everything"})
+	assert.Error(t, err)
+
+	_, err = InitCustom(&Options{DisableFileLogging: true, ConsoleFormat: "html"})
+	assert.Error(t, err)
+}
+
+func Test_JsonEntriesContainStandardFields(t *testing.T) {
+	// Arrange
+	logFile := "test_file.log"
+	testMessage := uuid.New().String()
+	viper.Set("environment-tag", "dev")
+	defer viper.Set("environment-tag", "")
+	SetCurrentCliCommand("hq infrastructure deploy")
+	defer SetCurrentCliCommand("")
+
+	// Act
+	_, err := InitCustom(&Options{LogFileName: logFile, FileFormat: FormatJSON, ProgramName: "hq", Version: "1.2.3"})
+	logrus.WithField("resourceGroup", "my-rg").Warn(testMessage)
+	Init(logFile)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, Flush())
+
+	content, _ := os.ReadFile(logFile)
+	var entry map[string]any
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, testMessage) {
+			assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		}
+	}
+
+	assert.Equal(t, testMessage, entry[FieldMessage])
+	assert.Equal(t, "warning", entry[FieldLevel])
+	assert.NotEmpty(t, entry[FieldTimestamp])
+	assert.Equal(t, "hq", entry[FieldProgram])
+	assert.Equal(t, "1.2.3", entry[FieldVersion])
+	assert.Equal(t, RunId(), entry[FieldRunId])
+	assert.Equal(t, "dev", entry[FieldEnvironmentTag])
+	assert.Equal(t, "hq infrastructure deploy", entry[FieldCliCommand])
+	assert.Equal(t, "my-rg", entry["resourceGroup"])
+	os.Remove(logFile)
+}
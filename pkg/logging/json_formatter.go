@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Field names of the JSON log entries (see FormatJSON). The names are stable, so that log queries can rely on them.
+const (
+	FieldTimestamp      = "timestamp"
+	FieldLevel          = "level"
+	FieldMessage        = "message"
+	FieldProgram        = "program"
+	FieldVersion        = "version"
+	FieldRunId          = "runId"
+	FieldEnvironmentTag = "environmentTag"
+	FieldCliCommand     = "cliCommand"
+	FieldCommand        = "command"
+	FieldStream         = "stream"
+)
+
+var (
+	standardFieldsMutex sync.Mutex
+	programName         string
+	programVersion      string
+	currentCliCommand   string
+
+	runId = uuid.New().String()
+)
+
+// RunId returns the unique ID of the current program run, which is added to all JSON log entries
+func RunId() string {
+	return runId
+}
+
+// SetCurrentCliCommand sets the CLI command currently executed (e.g. "my-app infrastructure deploy"), which is added
+// to all JSON log entries. Called by the cli package, before a command is executed.
+func SetCurrentCliCommand(command string) {
+	standardFieldsMutex.Lock()
+	defer standardFieldsMutex.Unlock()
+
+	currentCliCommand = command
+}
+
+// CurrentCliCommand returns the CLI command currently executed, as set via SetCurrentCliCommand
+func CurrentCliCommand() string {
+	standardFieldsMutex.Lock()
+	defer standardFieldsMutex.Unlock()
+
+	return currentCliCommand
+}
+
+func setProgram(name string, version string) {
+	standardFieldsMutex.Lock()
+	defer standardFieldsMutex.Unlock()
+
+	programName = name
+	programVersion = version
+}
+
+// standardFields returns the fields added to every JSON log entry. Empty fields are omitted.
+func standardFields() logrus.Fields {
+	standardFieldsMutex.Lock()
+	defer standardFieldsMutex.Unlock()
+
+	fields := logrus.Fields{FieldRunId: runId}
+
+	values := map[string]string{
+		FieldProgram:        programName,
+		FieldVersion:        programVersion,
+		FieldEnvironmentTag: viper.GetString("environment-tag"),
+		FieldCliCommand:     currentCliCommand,
+	}
+
+	for name, value := range values {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+
+	return fields
+}
+
+// jsonFormatter formats the entries as JSON lines, including the standard fields
+type jsonFormatter struct {
+	formatter *logrus.JSONFormatter
+}
+
+func newJsonFormatter() *jsonFormatter {
+	return &jsonFormatter{
+		formatter: &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyTime:  FieldTimestamp,
+				logrus.FieldKeyLevel: FieldLevel,
+				logrus.FieldKeyMsg:   FieldMessage,
+			},
+		},
+	}
+}
+
+func (f *jsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := standardFields()
+	for name, value := range entry.Data {
+		data[name] = value
+	}
+
+	withStandardFields := *entry
+	withStandardFields.Data = data
+
+	return f.formatter.Format(&withStandardFields)
+}
+
+// formatOutput formats a line of command output as JSON log entry, tagged with the command and the stream
+func (f *jsonFormatter) formatOutput(line string, command string, stream string) []byte {
+	data := logrus.Fields{}
+	if command != "" {
+		data[FieldCommand] = command
+	}
+
+	if stream != "" {
+		data[FieldStream] = stream
+	}
+
+	formatted, err := f.Format(&logrus.Entry{
+		Logger:  logrus.StandardLogger(),
+		Data:    data,
+		Time:    time.Now(),
+		Level:   logrus.InfoLevel,
+		Message: line,
+	})
+
+	if err != nil {
+		// cannot happen for string fields, but the line should never be lost
+		return []byte(line + "\n")
+	}
+
+	return formatted
+}
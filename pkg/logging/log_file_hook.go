@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"github.com/conplementag/cops-hq/v2/internal/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// logFileHook writes the log entries to the shared log file, which is also used for the command output of the
+// executor. Using the same writer makes sure the file is rotated only once, and entries never interleave with the
+// command output.
+type logFileHook struct {
+	logFile   *logging.LogFile
+	formatter logrus.Formatter
+	level     logrus.Level
+}
+
+func (h *logFileHook) Levels() []logrus.Level {
+	return logrus.AllLevels[:h.level+1]
+}
+
+func (h *logFileHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.logFile.Write(line)
+	return err
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Format of the logged entries
+type Format string
+
+const (
+	// FormatText is the default human-readable format, with colors on the console
+	FormatText Format = "text"
+
+	// FormatPlainText is same as FormatText, but without any colors (e.g. for consoles collected by a log aggregator)
+	FormatPlainText Format = "plain"
+
+	// FormatJSON writes every entry as a JSON line, including the standard fields (program, version, run ID,
+	// environment tag and CLI command). The command output is written as separate entries per line, tagged with the
+	// command and the stream (stdout or stderr). Check the Field... constants for the field names.
+	FormatJSON Format = "json"
+)
+
+// Options are used to initialize the logging system via InitCustom
+type Options struct {
+	// LogFileName of the log file, required unless file logging is disabled
+	LogFileName string
+
+	// DisableFileLogging logs to the console only, e.g. for containers with a read-only filesystem
+	DisableFileLogging bool
+
+	// Level is the minimal level of the logged entries (e.g. "debug" or "warning"). Empty (default) means
+	// DefaultLogLevel.
+	Level string
+
+	// ConsoleFormat of the console output. Empty (default) means FormatText.
+	ConsoleFormat Format
+
+	// FileFormat of the log file. Empty (default) means FormatPlainText, FormatText is the same for files.
+	FileFormat Format
+
+	// DisableCIIntegration turns off the automatic integration with Azure DevOps and GitHub Actions, which groups the
+	// console output into collapsible sections, and shows errors and warnings as annotations (see StartGroup)
+	DisableCIIntegration bool
+
+	// ProgramName and Version are added to the JSON log entries (see FormatJSON)
+	ProgramName string
+	Version     string
+}
+
+func (options *Options) Validate() error {
+	if options.LogFileName == "" && !options.DisableFileLogging {
+		return errors.New("you need to define the logFileName if logging to the file is enabled")
+	}
+
+	if options.Level != "" {
+		if _, err := logrus.ParseLevel(options.Level); err != nil {
+			return err
+		}
+	}
+
+	if err := options.ConsoleFormat.validate(); err != nil {
+		return fmt.Errorf("invalid console format: %w", err)
+	}
+
+	if err := options.FileFormat.validate(); err != nil {
+		return fmt.Errorf("invalid file format: %w", err)
+	}
+
+	return nil
+}
+
+func (format Format) validate() error {
+	switch format {
+	case "", FormatText, FormatPlainText, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, supported are %q, %q and %q", format, FormatText, FormatPlainText,
+			FormatJSON)
+	}
+}
+
+func (options *Options) level() logrus.Level {
+	if options.Level == "" {
+		return DefaultLogLevel
+	}
+
+	level, _ := logrus.ParseLevel(options.Level)
+	return level
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"github.com/conplementag/cops-hq/v2/internal/secrets"
+	"github.com/sirupsen/logrus"
+)
+
+// redactionHook masks all registered secrets (see commands.Executor RegisterSecret) in the log entries. It needs to
+// be registered before any other hook which writes the entries (like the file rotation hook).
+type redactionHook struct{}
+
+func (h *redactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = secrets.Redact(entry.Message)
+
+	for key, value := range entry.Data {
+		switch typedValue := value.(type) {
+		case string:
+			entry.Data[key] = secrets.Redact(typedValue)
+		case error:
+			entry.Data[key] = secrets.Redact(typedValue.Error())
+		}
+	}
+
+	return nil
+}
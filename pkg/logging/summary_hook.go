@@ -0,0 +1,19 @@
+package logging
+
+import (
+	"github.com/conplementag/cops-hq/v2/internal/summary"
+	"github.com/sirupsen/logrus"
+)
+
+// summaryHook records the logged warnings in the run summary of HQ. Nothing is recorded, if the program is not run
+// with HQ.
+type summaryHook struct{}
+
+func (h *summaryHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (h *summaryHook) Fire(entry *logrus.Entry) error {
+	summary.RecordWarning(entry.Message)
+	return nil
+}
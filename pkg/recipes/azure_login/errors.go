@@ -0,0 +1,35 @@
+package azure_login
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotLoggedIn is matched (errors.Is) by the LoginError returned if the login failed
+	ErrNotLoggedIn = errors.New("not logged in to Azure")
+
+	// ErrTenantRequired is returned by Login, if the tenant of the managed identity or service principal is missing
+	ErrTenantRequired = errors.New("tenant must be given")
+
+	// ErrSecretRequired is returned by Login, if the service principal is given without a secret
+	ErrSecretRequired = errors.New("service principal secret must be given")
+)
+
+// LoginError is returned if the login failed. It matches ErrNotLoggedIn, and the error of the failed az login command
+// (e.g. a commands.ExitError) can be retrieved with errors.As.
+type LoginError struct {
+	// Method is the login method, e.g. "azure service principal"
+	Method string
+
+	// Cause is the error of the failed login
+	Cause error
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("errors while logging in via %s: %v", e.Method, e.Cause)
+}
+
+func (e *LoginError) Unwrap() []error {
+	return []error{ErrNotLoggedIn, e.Cause}
+}
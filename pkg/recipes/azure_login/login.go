@@ -2,14 +2,11 @@ package azure_login
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/conplementag/cops-hq/v2/internal"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
-	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/sirupsen/logrus"
 )
 
@@ -33,32 +30,33 @@ type Login struct {
 func (l *Login) Login() error {
 	if l.useUserAssignedManagedIdentityLogin() {
 		if l.managedIdentityTenantId == "" {
-			return errors.New("tenant must be given, when using user assigned managed identity")
+			return fmt.Errorf("%w, when using user assigned managed identity", ErrTenantRequired)
 		}
 
 		logrus.Info("Login as user assigned managed identity: " + l.userAssignedManagedIdentityClientId)
 		err := l.userAssignedManagedIdentityLogin(l.userAssignedManagedIdentityClientId, l.managedIdentityTenantId)
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(), err)
 	} else if l.useSystemAssignedManagedIdentityLogin() {
 		if l.managedIdentityTenantId == "" {
-			return errors.New("tenant must be given, when using system assigned managed identity")
+			return fmt.Errorf("%w, when using system assigned managed identity", ErrTenantRequired)
 		}
 
 		logrus.Info("Login as system assigned managed identity")
 		err := l.systemAssignedManagedIdentityLogin(l.managedIdentityTenantId)
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(), err)
 	} else if l.useServicePrincipalLogin() {
 		if l.servicePrincipalSecret == "" {
-			return internal.ReturnErrorOrPanic(errors.New("service principal secret must be given, when using service principal credentials"))
+			return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(),
+				fmt.Errorf("%w, when using service principal credentials", ErrSecretRequired))
 		}
 
 		if l.servicePrincipalTenantId == "" {
-			return errors.New("tenant must be given, when using service principal credentials")
+			return fmt.Errorf("%w, when using service principal credentials", ErrTenantRequired)
 		}
 
 		logrus.Info("Login as service-principal: " + l.servicePrincipalId)
 		err := l.servicePrincipalLogin(l.servicePrincipalId, l.servicePrincipalSecret, l.servicePrincipalTenantId)
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(), err)
 	} else {
 		loggedIn, err := l.isUserAlreadyLoggedIn()
 
@@ -69,7 +67,7 @@ func (l *Login) Login() error {
 
 		if !loggedIn {
 			logrus.Info("Login as user interactive")
-			return internal.ReturnErrorOrPanic(l.interactiveLogin())
+			return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(), l.interactiveLogin())
 		} else {
 			logrus.Info("User is already logged in")
 		}
@@ -78,18 +76,20 @@ func (l *Login) Login() error {
 	return nil
 }
 
-// SetSubscription sets the current Azure subscription on the running system (for Azure CLI & Terraform)
+// SetSubscription sets the current Azure subscription for the Azure CLI, and for Terraform commands executed with the
+// executor of this login (see commands.Executor SetEnv)
 func (l *Login) SetSubscription(subscriptionId string) error {
 	logrus.Info("Setting current Azure subscription to: " + subscriptionId)
-	_, err := l.executor.Execute("az account set -s " + subscriptionId)
+	// the Azure CLI login commands only change the local CLI session, so they are executed in dry-run mode as well
+	_, err := l.executor.ReadOnly().Execute("az account set -s " + subscriptionId)
 
-	errEnvVar := os.Setenv("ARM_SUBSCRIPTION_ID", subscriptionId)
-
-	if err != nil || errEnvVar != nil {
-		return internal.ReturnErrorOrPanic(fmt.Errorf("errors while setting the subscription: %v %v ",
-			err, errEnvVar))
+	if err != nil {
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(),
+			fmt.Errorf("errors while setting the subscription: %w", err))
 	}
 
+	l.executor.SetEnv(map[string]string{"ARM_SUBSCRIPTION_ID": subscriptionId})
+
 	return nil
 }
 
@@ -106,26 +106,36 @@ func (l *Login) useServicePrincipalLogin() bool {
 }
 
 func (l *Login) interactiveLogin() error {
-	_, err := l.executor.ExecuteLoud("az login")
-	return err
+	// the login commands are executed as try, so that a failure is raised as LoginError (with the policy of the
+	// executor), instead of as the plain command error
+	_, err := l.executor.ReadOnly().Try().ExecuteLoud("az login")
+	if err != nil {
+		return &LoginError{Method: "interactive user login", Cause: err}
+	}
+
+	return nil
 }
 
 func (l *Login) servicePrincipalLogin(servicePrincipal string, secret string, tenant string) error {
 	// First, we log into the Azure CLI
 	// see https://learn.microsoft.com/en-us/cli/azure/reference-index?view=azure-cli-latest#az-login hints for secrets starting with "-"
+	l.executor.RegisterSecret(secret)
 	commandText := "az login -u " + servicePrincipal + " -p=" + secret + " -t " + tenant + " --service-principal"
-	_, err := l.executor.ExecuteSilent(commandText)
-
-	// Then, we also need to set the env variables required for Terraform if working with service principals
-	err1 := os.Setenv("ARM_CLIENT_ID", servicePrincipal)
-	err2 := os.Setenv("ARM_CLIENT_SECRET", secret)
-	err3 := os.Setenv("ARM_TENANT_ID", tenant)
+	_, err := l.executor.ReadOnly().Try().ExecuteSilent(commandText)
 
-	if err != nil || err1 != nil || err2 != nil || err3 != nil {
-		return internal.ReturnErrorOrPanic(fmt.Errorf("errors while logging in via azure service principal: %v %v %v %v",
-			err, err1, err2, err3))
+	if err != nil {
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(), &LoginError{Method: "azure service principal",
+			Cause: err})
 	}
 
+	// Then, we also need to set the env variables required for Terraform if working with service principals. They are
+	// only passed to the commands of the executor, not set for the whole process
+	l.executor.SetEnv(map[string]string{
+		"ARM_CLIENT_ID":     servicePrincipal,
+		"ARM_CLIENT_SECRET": secret,
+		"ARM_TENANT_ID":     tenant,
+	})
+
 	return nil
 }
 
@@ -133,18 +143,20 @@ func (l *Login) userAssignedManagedIdentityLogin(userAssignedManagedIdentityClie
 	// First, we log into the Azure CLI
 	// see https://learn.microsoft.com/en-us/cli/azure/reference-index?view=azure-cli-latest#az-login hints for secrets starting with "-"
 	commandText := "az login --identity --client-id " + userAssignedManagedIdentityClientId
-	_, err := l.executor.Execute(commandText)
-
-	// Then, we also need to set the env variables required for Terraform if working with user assigned managed identities
-	err1 := os.Setenv("ARM_CLIENT_ID", userAssignedManagedIdentityClientId)
-	err2 := os.Setenv("ARM_USE_MSI", "true")
-	err3 := os.Setenv("ARM_TENANT_ID", tenant)
+	_, err := l.executor.ReadOnly().Try().Execute(commandText)
 
-	if err != nil || err1 != nil || err2 != nil || err3 != nil {
-		return internal.ReturnErrorOrPanic(fmt.Errorf("errors while logging in via user assigned managed identity: %v %v %v %v",
-			err, err1, err2, err3))
+	if err != nil {
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(),
+			&LoginError{Method: "user assigned managed identity", Cause: err})
 	}
 
+	// Then, we also need to set the env variables required for Terraform if working with user assigned managed identities
+	l.executor.SetEnv(map[string]string{
+		"ARM_CLIENT_ID": userAssignedManagedIdentityClientId,
+		"ARM_USE_MSI":   "true",
+		"ARM_TENANT_ID": tenant,
+	})
+
 	return nil
 }
 
@@ -152,28 +164,25 @@ func (l *Login) systemAssignedManagedIdentityLogin(tenant string) error {
 	// First, we log into the Azure CLI
 	// see https://learn.microsoft.com/en-us/cli/azure/reference-index?view=azure-cli-latest#az-login hints for secrets starting with "-"
 	commandText := "az login --identity"
-	_, err := l.executor.Execute(commandText)
-
-	// Then, we also need to set the env variables required for Terraform if working with system assigned managed identities
-	err1 := os.Setenv("ARM_USE_MSI", "true")
-	err2 := os.Setenv("ARM_TENANT_ID", tenant)
+	_, err := l.executor.ReadOnly().Try().Execute(commandText)
 
-	if err != nil || err1 != nil || err2 != nil {
-		return internal.ReturnErrorOrPanic(fmt.Errorf("errors while logging in via system assigned managed identity: %v %v %v",
-			err, err1, err2))
+	if err != nil {
+		return internal.ReturnErrorOrPanicWith(l.executor.ErrorPolicy(),
+			&LoginError{Method: "system assigned managed identity", Cause: err})
 	}
 
+	// Then, we also need to set the env variables required for Terraform if working with system assigned managed identities
+	l.executor.SetEnv(map[string]string{
+		"ARM_USE_MSI":   "true",
+		"ARM_TENANT_ID": tenant,
+	})
+
 	return nil
 }
 
 func (l *Login) isUserAlreadyLoggedIn() (bool, error) {
-	// since we actually rely on errors to test if user is logged in, we will shortly suppress the executor panics
-	previousPanicSetting := error_handling.PanicOnAnyError
-	error_handling.PanicOnAnyError = false
-
-	output, err := l.executor.ExecuteSilent("az account show")
-
-	error_handling.PanicOnAnyError = previousPanicSetting
+	// since we actually rely on errors to test if user is logged in, the command is executed as try
+	output, err := l.executor.ReadOnly().Try().ExecuteSilent("az account show")
 
 	if err != nil {
 		return false, err
@@ -8,18 +8,14 @@ import (
 	"testing"
 
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/commands/commandstest"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func Test_TriggersServicePrincipalLogin_WhenIdProvided(t *testing.T) {
 	// Arrange
-	t.Cleanup(func() {
-		os.Unsetenv("ARM_TENANT_ID")
-		os.Unsetenv("ARM_CLIENT_ID")
-		os.Unsetenv("ARM_CLIENT_SECRET")
-		os.Unsetenv("ARM_USE_MSI")
-	})
 	executor := &loginExecutorMock{}
 	azureLogin := NewWithParams(executor, "sp-client-id", "sp-client-secret", "sp-tenantId", "", "", false)
 
@@ -31,16 +27,47 @@ func Test_TriggersServicePrincipalLogin_WhenIdProvided(t *testing.T) {
 	azureLogin.Login()
 
 	// Assert
-	assert.Equal(t, os.Getenv("ARM_TENANT_ID"), "sp-tenantId")
-	assert.Equal(t, os.Getenv("ARM_CLIENT_ID"), "sp-client-id")
-	assert.Equal(t, os.Getenv("ARM_CLIENT_SECRET"), "sp-client-secret")
-	assert.Equal(t, os.Getenv("ARM_USE_MSI"), "")
+	assert.Equal(t, "sp-tenantId", executor.env["ARM_TENANT_ID"])
+	assert.Equal(t, "sp-client-id", executor.env["ARM_CLIENT_ID"])
+	assert.Equal(t, "sp-client-secret", executor.env["ARM_CLIENT_SECRET"])
+	assert.Equal(t, "", executor.env["ARM_USE_MSI"])
 	executor.AssertExpectations(t)
 }
 
+func Test_LoginDoesNotChangeTheProcessEnvironment(t *testing.T) {
+	// Arrange
+	fake := commandstest.NewFakeExecutor()
+	azureLogin := NewWithParams(fake, "sp-client-id", "sp-client-secret", "sp-tenantId", "", "", false)
+
+	// Act
+	err := azureLogin.Login()
+	errSubscription := azureLogin.SetSubscription("subscription-id")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, errSubscription)
+	assert.Empty(t, os.Getenv("ARM_CLIENT_SECRET"))
+	assert.Empty(t, os.Getenv("ARM_CLIENT_ID"))
+	assert.Empty(t, os.Getenv("ARM_SUBSCRIPTION_ID"))
+	assert.Equal(t, "sp-client-secret", fake.Env()["ARM_CLIENT_SECRET"])
+	assert.Equal(t, "subscription-id", fake.Env()["ARM_SUBSCRIPTION_ID"])
+}
+
+func Test_ServicePrincipalSecretIsRegisteredForMasking(t *testing.T) {
+	// Arrange
+	executor := &loginExecutorMock{}
+	azureLogin := NewWithParams(executor, "sp-client-id", "sp-client-secret", "sp-tenantId", "", "", false)
+	executor.On("ExecuteSilent", mock.Anything)
+
+	// Act
+	azureLogin.Login()
+
+	// Assert
+	assert.Contains(t, executor.registeredSecrets, "sp-client-secret")
+}
+
 func Test_TriggersUserAssignedManagedIdentityLogin_WhenClientIdAndFlagProvided(t *testing.T) {
 	// Arrange
-	CleanUpAfter(t)
 	executor := &loginExecutorMock{}
 	azureLogin := NewWithParams(executor, "sp-client-id", "secret", "sp-tenantId", "umi-clientid", "mi-tenantId", true)
 
@@ -52,15 +79,14 @@ func Test_TriggersUserAssignedManagedIdentityLogin_WhenClientIdAndFlagProvided(t
 	azureLogin.Login()
 
 	// Assert
-	assert.Equal(t, os.Getenv("ARM_TENANT_ID"), "mi-tenantId")
-	assert.Equal(t, os.Getenv("ARM_CLIENT_ID"), "umi-clientid")
-	assert.Equal(t, os.Getenv("ARM_USE_MSI"), "true")
+	assert.Equal(t, "mi-tenantId", executor.env["ARM_TENANT_ID"])
+	assert.Equal(t, "umi-clientid", executor.env["ARM_CLIENT_ID"])
+	assert.Equal(t, "true", executor.env["ARM_USE_MSI"])
 	executor.AssertExpectations(t)
 }
 
 func Test_TriggersSystemAssignedManagedIdentityLogin_WhenOnlyFlagProvided(t *testing.T) {
 	// Arrange
-	CleanUpAfter(t)
 	executor := &loginExecutorMock{}
 	azureLogin := NewWithParams(executor, "sp-client-id", "sp-client-secret", "sp-tenantId", "", "mi-tenantId", true)
 
@@ -72,15 +98,14 @@ func Test_TriggersSystemAssignedManagedIdentityLogin_WhenOnlyFlagProvided(t *tes
 	azureLogin.Login()
 
 	// Assert
-	assert.Equal(t, os.Getenv("ARM_TENANT_ID"), "mi-tenantId")
-	assert.Equal(t, os.Getenv("ARM_CLIENT_ID"), "")
-	assert.Equal(t, os.Getenv("ARM_USE_MSI"), "true")
+	assert.Equal(t, "mi-tenantId", executor.env["ARM_TENANT_ID"])
+	assert.Equal(t, "", executor.env["ARM_CLIENT_ID"])
+	assert.Equal(t, "true", executor.env["ARM_USE_MSI"])
 	executor.AssertExpectations(t)
 }
 
 func Test_TriggersServicePrincipalLogin_WhenIdProvidedAndUamIdProvidedButMiFlagNotProvided(t *testing.T) {
 	// Arrange
-	CleanUpAfter(t)
 	executor := &loginExecutorMock{}
 	azureLogin := NewWithParams(executor, "sp-client-id", "sp-client-secret", "sp-tenantId", "umi-clientid", "mi-tenantId", false)
 
@@ -92,16 +117,15 @@ func Test_TriggersServicePrincipalLogin_WhenIdProvidedAndUamIdProvidedButMiFlagN
 	azureLogin.Login()
 
 	// Assert
-	assert.Equal(t, os.Getenv("ARM_TENANT_ID"), "sp-tenantId")
-	assert.Equal(t, os.Getenv("ARM_CLIENT_ID"), "sp-client-id")
-	assert.Equal(t, os.Getenv("ARM_CLIENT_SECRET"), "sp-client-secret")
-	assert.Equal(t, os.Getenv("ARM_USE_MSI"), "")
+	assert.Equal(t, "sp-tenantId", executor.env["ARM_TENANT_ID"])
+	assert.Equal(t, "sp-client-id", executor.env["ARM_CLIENT_ID"])
+	assert.Equal(t, "sp-client-secret", executor.env["ARM_CLIENT_SECRET"])
+	assert.Equal(t, "", executor.env["ARM_USE_MSI"])
 	executor.AssertExpectations(t)
 }
 
 func Test_TriggersNoLogin_WhenUserAlreadyLoggedIn(t *testing.T) {
 	// Arrange
-	CleanUpAfter(t)
 	executor := &loginExecutorMock{}
 	executor.userLoggedIn = true
 
@@ -120,7 +144,6 @@ func Test_TriggersNoLogin_WhenUserAlreadyLoggedIn(t *testing.T) {
 
 func Test_TriggersUserLogin_WhenNoCredentialsProvidedAndNotLoggedIn(t *testing.T) {
 	// Arrange
-	CleanUpAfter(t)
 	executor := &loginExecutorMock{}
 	executor.userLoggedIn = false
 
@@ -143,19 +166,36 @@ func Test_TriggersUserLogin_WhenNoCredentialsProvidedAndNotLoggedIn(t *testing.T
 	executor.AssertExpectations(t)
 }
 
-func CleanUpAfter(t *testing.T) {
-	t.Cleanup(func() {
-		os.Unsetenv("ARM_TENANT_ID")
-		os.Unsetenv("ARM_CLIENT_ID")
-		os.Unsetenv("ARM_CLIENT_SECRET")
-		os.Unsetenv("ARM_USE_MSI")
-	})
+func Test_FailedLoginMatchesErrNotLoggedIn(t *testing.T) {
+	// Arrange
+	fake := commandstest.NewFakeExecutor()
+	fake.OnPrefix("az login").Returns("").WithStderr("AADSTS7000215: Invalid client secret provided.").WithExitCode(1)
+
+	azureLogin := NewWithParams(fake, "sp-client-id", "sp-client-secret", "sp-tenantId", "", "", false)
+
+	// Act
+	err := azureLogin.Login()
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotLoggedIn)
+
+	var exitErr *commands.ExitError
+	if assert.ErrorAs(t, err, &exitErr) {
+		assert.Equal(t, 1, exitErr.ExitCode)
+	}
+}
+
+func Test_MissingTenantReturnsErrTenantRequired(t *testing.T) {
+	azureLogin := NewWithParams(commandstest.NewFakeExecutor(), "sp-client-id", "sp-client-secret", "", "", "", false)
+	assert.ErrorIs(t, azureLogin.Login(), ErrTenantRequired)
 }
 
 type loginExecutorMock struct {
 	mock.Mock
 	commands.Executor
-	userLoggedIn bool
+	userLoggedIn      bool
+	registeredSecrets []string
+	env               map[string]string
 }
 
 func (e *loginExecutorMock) setUserLoggedIn(userLoggedIn bool) {
@@ -202,6 +242,32 @@ func (e *loginExecutorMock) ExecuteSilent(command string) (string, error) {
 	return "unknown command for the ExecuteSilent mock called, but let's return successfully anyways", nil
 }
 
+func (e *loginExecutorMock) ReadOnly() commands.Executor {
+	return e
+}
+
+func (e *loginExecutorMock) Try() commands.Executor {
+	return e
+}
+
+func (e *loginExecutorMock) ErrorPolicy() error_handling.Policy {
+	return error_handling.Default
+}
+
+func (e *loginExecutorMock) RegisterSecret(value string) {
+	e.registeredSecrets = append(e.registeredSecrets, value)
+}
+
+func (e *loginExecutorMock) SetEnv(env map[string]string) {
+	if e.env == nil {
+		e.env = make(map[string]string)
+	}
+
+	for name, value := range env {
+		e.env[name] = value
+	}
+}
+
 func (e *loginExecutorMock) ExecuteLoud(command string) (string, error) {
 	e.Called(command)
 
@@ -29,6 +29,7 @@ type copsctl struct {
 func (c *copsctl) Connect(clusterName string, clusterConnectionString string, isTechnicalAccountConnect bool, connectToSecondaryCluster bool) error {
 	logrus.Info("[Cluster] Connecting to cluster " + clusterName + " ...")
 
+	c.executor.RegisterSecret(clusterConnectionString)
 	copsConnectCmd := "copsctl connect -e " + clusterName + " -c \"" + clusterConnectionString + "\" -a"
 
 	if isTechnicalAccountConnect {
@@ -39,18 +40,19 @@ func (c *copsctl) Connect(clusterName string, clusterConnectionString string, is
 		copsConnectCmd = copsConnectCmd + " -s"
 	}
 
-	_, err := c.executor.Execute(copsConnectCmd)
+	// connecting only changes the local kube config, and is required for any further queries in dry-run mode as well
+	_, err := c.executor.ReadOnly().Execute(copsConnectCmd)
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(c.executor.ErrorPolicy(), err)
 	}
 
 	// workaround to force kubectl login for interactive-login mode
 	if !isTechnicalAccountConnect {
-		err = c.executor.ExecuteTTY("kubectl auth can-i list copsnamespaces.coreops.conplement.cloud") // this query should always work in copsctl context
+		err = c.executor.ReadOnly().ExecuteTTY("kubectl auth can-i list copsnamespaces.coreops.conplement.cloud") // this query should always work in copsctl context
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(c.executor.ErrorPolicy(), err)
 		}
 	}
 
@@ -61,17 +63,17 @@ func (c *copsctl) Connect(clusterName string, clusterConnectionString string, is
 func (c *copsctl) GetEnvironmentInfo() (*EnvironmentInfoV2, error) {
 	logrus.Info("Receiving environment info...")
 
-	environmentInfoJson, err := c.executor.ExecuteSilent("copsctl info environment --print-to-stdout-silence-everything-else")
+	environmentInfoJson, err := c.executor.ReadOnly().ExecuteSilent("copsctl info environment --print-to-stdout-silence-everything-else")
 
 	if err != nil {
-		return nil, internal.ReturnErrorOrPanic(err)
+		return nil, internal.ReturnErrorOrPanicWith(c.executor.ErrorPolicy(), err)
 	}
 
 	var environmentInfo EnvironmentInfoV2
 	err = json.Unmarshal([]byte(environmentInfoJson), &environmentInfo)
 
 	if err != nil {
-		return nil, internal.ReturnErrorOrPanic(err)
+		return nil, internal.ReturnErrorOrPanicWith(c.executor.ErrorPolicy(), err)
 	}
 
 	logrus.Info("Done.")
@@ -82,17 +84,17 @@ func (c *copsctl) GetEnvironmentInfo() (*EnvironmentInfoV2, error) {
 func (c *copsctl) GetClusterInfo() (*ClusterInfoV1, error) {
 	logrus.Info("Receiving cluster info...")
 
-	clusterInfoJson, err := c.executor.ExecuteSilent("copsctl info cluster --print-to-stdout-silence-everything-else")
+	clusterInfoJson, err := c.executor.ReadOnly().ExecuteSilent("copsctl info cluster --print-to-stdout-silence-everything-else")
 
 	if err != nil {
-		return nil, internal.ReturnErrorOrPanic(err)
+		return nil, internal.ReturnErrorOrPanicWith(c.executor.ErrorPolicy(), err)
 	}
 
 	var clusterInfo ClusterInfoV1
 	err = json.Unmarshal([]byte(clusterInfoJson), &clusterInfo)
 
 	if err != nil {
-		return nil, internal.ReturnErrorOrPanic(err)
+		return nil, internal.ReturnErrorOrPanicWith(c.executor.ErrorPolicy(), err)
 	}
 
 	logrus.Info("Done.")
@@ -1,7 +1,7 @@
 package copsctl
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/ahmetb/go-linq/v4"
 )
@@ -61,7 +61,7 @@ func (info *EnvironmentInfoV2) GetDevOpsTeamSubnets(devOpsTeamName string) (subn
 	subnetBlue = &subnetBlueResult
 
 	if !ok {
-		return nil, nil, errors.New("Subnet blue for team " + devOpsTeamName + " not found!")
+		return nil, nil, fmt.Errorf("Subnet blue for team %s %w!", devOpsTeamName, ErrNotFound)
 	}
 
 	subnetGreenResult, ok := linq.From(info.NetworkingGreen.ApplicationSubnets).SingleWithT(func(subnet Subnet) bool {
@@ -71,7 +71,7 @@ func (info *EnvironmentInfoV2) GetDevOpsTeamSubnets(devOpsTeamName string) (subn
 	subnetGreen = &subnetGreenResult
 
 	if !ok {
-		return nil, nil, errors.New("Subnet green for team " + devOpsTeamName + " not found!")
+		return nil, nil, fmt.Errorf("Subnet green for team %s %w!", devOpsTeamName, ErrNotFound)
 	}
 
 	return subnetBlue, subnetGreen, nil
@@ -87,7 +87,7 @@ func (info *EnvironmentInfoV2) GetPrivateDnsZones(name string) (zoneBlue *Privat
 	zoneBlue = &zoneBlueResult
 
 	if !ok {
-		return nil, nil, errors.New("Private DNS Zone blue for name " + name + " not found!")
+		return nil, nil, fmt.Errorf("Private DNS Zone blue for name %s %w!", name, ErrNotFound)
 	}
 
 	zoneGreenResult, ok := linq.From(info.NetworkingGreen.PrivateDnsZones).SingleWithT(func(zone PrivateDnsZone) bool {
@@ -97,7 +97,7 @@ func (info *EnvironmentInfoV2) GetPrivateDnsZones(name string) (zoneBlue *Privat
 	zoneGreen = &zoneGreenResult
 
 	if !ok {
-		return nil, nil, errors.New("Private DNS Zone green for name " + name + " not found!")
+		return nil, nil, fmt.Errorf("Private DNS Zone green for name %s %w!", name, ErrNotFound)
 	}
 
 	return zoneBlue, zoneGreen, nil
@@ -110,7 +110,7 @@ func (info *EnvironmentInfoV2) GetApplicationDnsZone(devOpsTeamName string) (*Ap
 	}).(ApplicationDnsZone)
 
 	if !ok {
-		return nil, errors.New("Application DNS Zone for team " + devOpsTeamName + " not found!")
+		return nil, fmt.Errorf("Application DNS Zone for team %s %w!", devOpsTeamName, ErrNotFound)
 	}
 
 	return &zone, nil
@@ -29,7 +29,7 @@ func Test_Subnets_SearchingForNonExistingDevOpsTeam(t *testing.T) {
 	subnetBlue, subnetGreen, err := clusterInfo.GetDevOpsTeamSubnets("does-not-exist")
 
 	// Assert
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
 	assert.Nil(t, subnetBlue)
 	assert.Nil(t, subnetGreen)
 }
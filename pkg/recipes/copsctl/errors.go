@@ -0,0 +1,7 @@
+package copsctl
+
+import "errors"
+
+// ErrNotFound is returned by the EnvironmentInfoV2 lookups, if the requested subnet or DNS zone is not part of the
+// environment
+var ErrNotFound = errors.New("not found")
@@ -0,0 +1,6 @@
+package helm
+
+import "errors"
+
+// ErrWaitForJobsWithoutWait is returned by Deploy, if the deployment setting WaitForJobs is enabled without Wait
+var ErrWaitForJobsWithoutWait = errors.New("[CopsHq][Helm] deployment setting 'WaitForJobs' could not be enabled without enabled 'Wait' flag")
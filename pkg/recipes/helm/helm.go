@@ -1,9 +1,9 @@
 package helm
 
 import (
-	"errors"
 	"fmt"
 	"github.com/conplementag/cops-hq/v2/internal"
+	"github.com/conplementag/cops-hq/v2/internal/summary"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -46,14 +46,14 @@ func (h *helmWrapper) SetVariables(helmVariables map[string]interface{}) error {
 		data, err := yaml.Marshal(&helmVariables)
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(h.executor.ErrorPolicy(), err)
 		}
 
 		// file permission: owner: r,w - group: r - other: r -> 0644
 		err = ioutil.WriteFile(h.getValuesOverrideFilePath(), data, 0644)
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(h.executor.ErrorPolicy(), err)
 		}
 
 		h.variablesSet = true
@@ -64,7 +64,7 @@ func (h *helmWrapper) SetVariables(helmVariables map[string]interface{}) error {
 
 func (h *helmWrapper) Deploy() error {
 	if h.deploymentSettings.WaitForJobs && !h.deploymentSettings.Wait {
-		return errors.New("[CopsHq][Helm] deployment setting 'WaitForJobs' could not be enabled without enabled 'Wait' flag")
+		return ErrWaitForJobsWithoutWait
 	}
 
 	helmCmd := fmt.Sprintf("helm upgrade --namespace %s --install %s %s -f %s --timeout %s", h.namespace, h.chartName, h.helmDirectory, h.getValuesFilePath(), h.deploymentSettings.Timeout)
@@ -93,15 +93,22 @@ func (h *helmWrapper) Deploy() error {
 		helmCmd = fmt.Sprintf("%s --force-conflicts --server-side true", helmCmd)
 	}
 
+	defer summary.StartStep(fmt.Sprintf("Helm deploy of release %s in namespace %s", h.chartName, h.namespace))()
+
 	var err error
 	if h.deploymentSettings.Wait {
-		_, err = h.executor.ExecuteWithProgressInfo(helmCmd)
+		step := fmt.Sprintf("Waiting for the helm release %s in namespace %s", h.chartName, h.namespace)
+		_, err = h.executor.ExecuteWithProgressStep(step, helmCmd)
 	} else {
 		_, err = h.executor.Execute(helmCmd)
 	}
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(h.executor.ErrorPolicy(), err)
+	}
+
+	if !h.deploymentSettings.DryRun && !commands.IsDryRun() {
+		summary.RecordHelmRelease(h.chartName, h.namespace)
 	}
 
 	return nil
@@ -112,7 +112,7 @@ func Test_DeployExecutesExpectedCommandWithWaitAndTimeout(t *testing.T) {
 	deploymentSettings.Timeout = 2 * time.Minute
 	h, executorMock := createWithDeploymentSettings("project", deploymentSettings)
 	// helm upgrade with wait and timeout value is expected
-	executorMock.On("ExecuteWithProgressInfo", mock.MatchedBy(func(command string) bool {
+	executorMock.On("ExecuteWithProgressStep", "Waiting for the helm release projecttest in namespace projecttest", mock.MatchedBy(func(command string) bool {
 		return strings.Contains(command, "helm upgrade") && strings.Contains(command, "--wait") && strings.Contains(command, "--timeout 2m0s")
 	})).Once()
 
@@ -162,8 +162,8 @@ func (e *executorMock) Execute(command string) (string, error) {
 	return "success", nil
 }
 
-func (e *executorMock) ExecuteWithProgressInfo(command string) (string, error) {
-	e.Called(command)
+func (e *executorMock) ExecuteWithProgressStep(step string, command string) (string, error) {
+	e.Called(step, command)
 	return "success", nil
 }
 
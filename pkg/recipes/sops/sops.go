@@ -1,12 +1,12 @@
 package sops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
 	"github.com/sirupsen/logrus"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
@@ -35,25 +35,22 @@ func (s *sopsWrapper) RegenerateMacValues(filePath string) error {
 		if !info.IsDir() && strings.HasSuffix(info.Name(), ".yaml") && !strings.HasPrefix(info.Name(), ".") {
 			logrus.Infof("Checking file: %s\n", path)
 
-			// we check by simply opening the file
+			// we check by simply opening the file, silently as the output is the decrypted content. A failing check is
+			// expected for files with invalid MAC values, so it must not panic
 			checkMacCommand := fmt.Sprintf("sops -d %s", path)
 
-			if _, err := s.executor.Execute(checkMacCommand); err != nil {
-				var exitError *exec.ExitError
+			if _, err := s.executor.ReadOnly().Try().ExecuteSilent(checkMacCommand); err != nil {
+				var exitError *commands.ExitError
 
 				if errors.As(err, &exitError) {
-					exitCode := exitError.ExitCode()
+					exitCode := exitError.ExitCode
 
 					// See https://github.com/mozilla/sops/blob/v3.6.1/cmd/sops/codes/codes.go#L19
 					if exitCode == 51 {
 						logrus.Infof("Regenerating sops MAC for: %s\n", path)
 
-						err := os.Setenv("EDITOR", "vim -es +\"norm Go\" +\":wq\"")
-						if err != nil {
-							return err
-						}
-
-						_, err = s.executor.Execute(fmt.Sprintf("sops --ignore-mac %s", path))
+						_, err := s.executor.Run(context.Background(), fmt.Sprintf("sops --ignore-mac %s", path),
+							commands.WithEnv(map[string]string{"EDITOR": "vim -es +\"norm Go\" +\":wq\""}))
 						if err != nil {
 							return err
 						}
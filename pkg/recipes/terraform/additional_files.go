@@ -7,7 +7,8 @@ import (
 
 // persistPlanInAdditionalFormatsOnDisk - we also persist the plan output to disk in both human-readable and json formats,
 // which can be later be processed, without requiring terraform init & terraform show separately to achieve the same result.
-func (tf *terraformWrapper) persistPlanInAdditionalFormatsOnDisk(planAsPlaintext string, terraformRelativePlanFilePath string) error {
+// The plan in json format is returned.
+func (tf *terraformWrapper) persistPlanInAdditionalFormatsOnDisk(planAsPlaintext string, terraformRelativePlanFilePath string) (string, error) {
 	// to persist the plan in other file formats, we need to convert the terraformRelativePlanFilePath to a path
 	// resolvable from where we are running at the moment (e.g. cmd/example-cli).
 	planFullFilePath := filepath.Join(tf.terraformDirectory, terraformRelativePlanFilePath)
@@ -16,33 +17,33 @@ func (tf *terraformWrapper) persistPlanInAdditionalFormatsOnDisk(planAsPlaintext
 	textFile, err := os.Create(planFullFilePath + ".txt")
 	defer textFile.Close()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	_, err = textFile.WriteString(planAsPlaintext)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 2. json form we need to get with an extra terraform call. Since init is already done, this will work
-	// also, we use the terraformRelativePlanFilePath since this is a terraform command, initialized with -chdir
-	jsonPlanOutput, err := tf.executor.Execute("terraform -chdir=" + tf.terraformDirectory + " show -json " + terraformRelativePlanFilePath)
+	// also, we use the terraformRelativePlanFilePath since this is a terraform command, executed in the terraform directory
+	jsonPlanOutput, err := tf.runTerraform(tf.executor.ReadOnly(), "show -json "+terraformRelativePlanFilePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	jsonFile, err := os.Create(planFullFilePath + ".json")
 	defer jsonFile.Close()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	_, err = jsonFile.WriteString(jsonPlanOutput)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return jsonPlanOutput, nil
 }
 
 // persistAnalysisResultOnDisk - we also run the plan analyzer and persist the result as a file, in case plan contains no changes.
@@ -0,0 +1,14 @@
+package terraform
+
+import "errors"
+
+var (
+	// ErrPlanNotApproved is returned by DeployFlow and DestroyFlow, if the user declined to apply the plan
+	ErrPlanNotApproved = errors.New("plan was not approved")
+
+	// ErrVariablesNotSet is returned by the terraform functions requiring the variables, if SetVariables was not called
+	ErrVariablesNotSet = errors.New("you should call SetVariables() before executing any of the terraform functions")
+
+	// ErrInvalidFlowOptions is returned by DeployFlow and DestroyFlow for contradicting options
+	ErrInvalidFlowOptions = errors.New("planOnly with useExistingPlan makes no sense as a combination")
+)
@@ -9,7 +9,7 @@ import (
 var PlansDirectory = ".plans"
 
 // GetLocalTerraformRelativePlanFilePath gets the plan file path, relative to the given terraform directory. Output of this method
-// is usually used with terraform commands, which already have the root directory set with -chdir
+// is usually used with terraform commands, which are already executed in the terraform directory
 func GetLocalTerraformRelativePlanFilePath(projectName string, terraformDirectory string, getForDestroyPlan bool) (string, error) {
 	return getLocalTerraformRelativePath(projectName, terraformDirectory, getForDestroyPlan, GetPlanFileName)
 }
@@ -31,7 +31,7 @@ func getLocalTerraformRelativePath(projectName string, terraformDirectory string
 		return "", internal.ReturnErrorOrPanic(err)
 	}
 
-	// terraform file paths are always relative to terraform path set on -chdir, which we set to root where the sources are located
+	// terraform file paths are always relative to the working directory of terraform, which we set to root where the sources are located
 	return filepath.Join(PlansDirectory, fileNameFunction(projectName, getForDestroyPlan)), nil
 }
 
@@ -0,0 +1,64 @@
+package terraform
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/conplementag/cops-hq/v2/internal/summary"
+	"github.com/sirupsen/logrus"
+)
+
+// jsonPlan is the part of the terraform plan in json format (terraform show -json) needed to count the changes
+type jsonPlan struct {
+	ResourceChanges []struct {
+		Change struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// recordPlannedChanges records the resource changes of the plan in the run summary. The summary is informational only,
+// so a plan which cannot be parsed is not treated as error.
+func (tf *terraformWrapper) recordPlannedChanges(jsonPlanOutput string, isDestroy bool) {
+	add, change, destroy, err := countPlannedChanges(jsonPlanOutput)
+	if err != nil {
+		logrus.Debugf("could not count the planned changes of the terraform project %s: %v", tf.projectName, err)
+		return
+	}
+
+	summary.RecordTerraformChanges(summary.TerraformChanges{
+		Project:   tf.projectName,
+		IsDestroy: isDestroy,
+		Add:       add,
+		Change:    change,
+		Destroy:   destroy,
+	})
+}
+
+// countPlannedChanges counts the resources to add, change and destroy, the same way as terraform does in the plan
+// output (a replaced resource counts as added and destroyed)
+func countPlannedChanges(jsonPlanOutput string) (add int, change int, destroy int, err error) {
+	var plan jsonPlan
+
+	if err := json.Unmarshal([]byte(jsonPlanOutput), &plan); err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, resourceChange := range plan.ResourceChanges {
+		actions := resourceChange.Change.Actions
+
+		if slices.Contains(actions, "create") {
+			add++
+		}
+
+		if slices.Contains(actions, "update") {
+			change++
+		}
+
+		if slices.Contains(actions, "delete") {
+			destroy++
+		}
+	}
+
+	return add, change, destroy, nil
+}
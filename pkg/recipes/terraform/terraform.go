@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,8 +15,8 @@ import (
 	"github.com/conplementag/cops-hq/v2/internal/cmdutil"
 	"github.com/conplementag/cops-hq/v2/internal/file_handling"
 	"github.com/conplementag/cops-hq/v2/internal/slice_helpers"
+	"github.com/conplementag/cops-hq/v2/internal/summary"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
-	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/conplementag/cops-hq/v2/pkg/recipes/terraform/file_paths"
 	"github.com/sirupsen/logrus"
 )
@@ -114,7 +115,12 @@ func (tf *terraformWrapper) Init() error {
 
 	tags := serializeTagsIntoCmdArgsList(tf.storageSettings.Tags)
 
+	// each step is shown as collapsible section in CI, and recorded with its duration in the run summary
+	var steps summary.Steps
+	defer steps.End()
+
 	if tf.storageSettings.CreateResourceGroup {
+		steps.Start("Deploying the resource group " + tf.resourceGroupName)
 		logrus.Info("Deploying the project " + tf.projectName + " resource group " + tf.resourceGroupName + "...")
 
 		groupCreateCmd := exec.Command("az", "group", "create",
@@ -134,10 +140,11 @@ func (tf *terraformWrapper) Init() error {
 		_, err := tf.executor.ExecuteCmd(groupCreateCmd)
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 	}
 
+	steps.Start("Deploying the terraform state storage account " + tf.stateStorageAccountName)
 	logrus.Info("Deploying the " + tf.projectName + " terraform state storage account " + tf.stateStorageAccountName + "...")
 
 	defaultAction := "Allow"
@@ -187,12 +194,12 @@ func (tf *terraformWrapper) Init() error {
 	_, err := tf.executor.ExecuteCmd(storageAccountCreateCmd)
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	err = tf.addStorageAccountNetworkRules()
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	defaultPortalAccessCmd := exec.Command("az", "storage", "account", "update",
@@ -202,7 +209,7 @@ func (tf *terraformWrapper) Init() error {
 
 	_, err = tf.executor.ExecuteCmd(defaultPortalAccessCmd)
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	fileSharePropertiesCmd := exec.Command("az", "storage", "account", "file-service-properties", "update",
@@ -215,34 +222,38 @@ func (tf *terraformWrapper) Init() error {
 
 	_, err = tf.executor.ExecuteCmd(fileSharePropertiesCmd)
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	logrus.Info("Reading the storage account key, which will be give to terraform to initialize the remote state...")
-	storageAccountKey, err := tf.executor.ExecuteSilent("az storage account keys list" +
+	storageAccountKey, err := tf.executor.ReadOnly().ExecuteSilent("az storage account keys list" +
 		" --resource-group " + tf.resourceGroupName +
 		" --account-name " + tf.stateStorageAccountName +
 		" --query [0].value -o tsv")
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	storageAccountKey = trimLinebreakSuffixes(storageAccountKey)
+	tf.executor.RegisterSecret(storageAccountKey)
 
+	steps.Start("Creating the remote state blob container " + tf.storageSettings.BlobContainerName)
 	logrus.Info("Creating the remote state blob container named " + tf.storageSettings.BlobContainerName + "...")
-	err = cmdutil.ExecuteWithRetry(
-		tf.executor.ExecuteSilent,
-		"az storage container create"+
-			" --account-name "+tf.stateStorageAccountName+
-			" --account-key "+storageAccountKey+
-			" --name "+tf.storageSettings.BlobContainerName,
-		tf.storageSettings.ContainerCreateRetryCount)
+	// network rules of the storage account might not be applied yet, which fails the container creation for a while
+	_, err = tf.executor.Run(context.Background(), "az storage container create"+
+		" --account-name "+tf.stateStorageAccountName+
+		" --account-key "+storageAccountKey+
+		" --name "+tf.storageSettings.BlobContainerName,
+		commands.WithSilentOutput(),
+		commands.WithRetry(commands.RetryPolicy{Attempts: tf.storageSettings.ContainerCreateRetryCount}))
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
+	steps.Start("Terraform init")
+
 	if tf.deploymentSettings.AlwaysCleanLocalCache {
 		logrus.Info("Clearing the terraform cache...")
 		err1 := os.RemoveAll(filepath.Join(tf.terraformDirectory, ".terraform"))
@@ -250,23 +261,23 @@ func (tf *terraformWrapper) Init() error {
 		err3 := os.RemoveAll(filepath.Join(tf.terraformDirectory, tf.GetVariablesFileName()))
 
 		if err1 != nil || err2 != nil || err3 != nil {
-			return internal.ReturnErrorOrPanic(fmt.Errorf("errors while clearing terraform cache: %v %v %v", err1, err2, err3))
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(),
+				fmt.Errorf("errors while clearing terraform cache: %v %v %v", err1, err2, err3))
 		}
 	}
 
 	logrus.Info("Terraform init...")
-	_, err = tf.executor.Execute("terraform" +
-		" -chdir=" + tf.terraformDirectory +
-		" init -upgrade " +
-		" --backend-config=subscription_id=" + tf.subscriptionId +
-		" --backend-config=tenant_id=" + tf.tenantId +
-		" --backend-config=storage_account_name=" + tf.stateStorageAccountName +
-		" --backend-config=access_key=" + storageAccountKey +
-		" --backend-config=container_name=" + tf.storageSettings.BlobContainerName +
-		" --backend-config=key=" + tf.storageSettings.BlobContainerKey)
+	// init only prepares the local working directory, so it is executed in dry-run mode as well (required for the plan)
+	_, err = tf.runTerraform(tf.executor.ReadOnly(), "init -upgrade "+
+		" --backend-config=subscription_id="+tf.subscriptionId+
+		" --backend-config=tenant_id="+tf.tenantId+
+		" --backend-config=storage_account_name="+tf.stateStorageAccountName+
+		" --backend-config=access_key="+storageAccountKey+
+		" --backend-config=container_name="+tf.storageSettings.BlobContainerName+
+		" --backend-config=key="+tf.storageSettings.BlobContainerKey)
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	return nil
@@ -281,7 +292,7 @@ func (tf *terraformWrapper) SetVariables(terraformVariables map[string]interface
 	f, err := os.Create(variablesPath)
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	defer f.Close()
@@ -290,13 +301,13 @@ func (tf *terraformWrapper) SetVariables(terraformVariables map[string]interface
 		valueJson, err := json.Marshal(value)
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 
 		_, err = f.WriteString(fmt.Sprintf("%s=%s\n", key, string(valueJson)))
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 	}
 
@@ -342,31 +353,27 @@ func (tf *terraformWrapper) GetVariablesFileName() string {
 
 func (tf *terraformWrapper) Output(parameterName *string) (string, error) {
 
-	tfCommand := "terraform" +
-		" -chdir=" + tf.terraformDirectory +
-		" output"
+	tfArguments := "output"
 
 	if parameterName != nil {
-		tfCommand += " -raw " + *parameterName
+		tfArguments += " -raw " + *parameterName
 	}
 
-	return tf.executor.Execute(tfCommand)
+	return tf.runTerraform(tf.executor.ReadOnly(), tfArguments)
 }
 
 func (tf *terraformWrapper) OutputAsJson() (string, error) {
 
-	tfCommand := "terraform" +
-		" -chdir=" + tf.terraformDirectory +
-		" output" +
+	tfArguments := "output" +
 		" -json"
 
-	return tf.executor.ExecuteSilent(tfCommand)
+	return tf.runTerraform(tf.executor.ReadOnly(), tfArguments, commands.WithSilentOutput())
 }
 
 func (tf *terraformWrapper) addStorageAccountNetworkRules() error {
-	existingIpAddresses, err := tf.determineCurrentAllowedIpAddresses()
+	existingIpAddresses, err := tf.determineCurrentAllowedIpAddresses(tf.executor)
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	addIpAddresses, removeIpAddresses := slice_helpers.FindItemsToAddAndRemove(existingIpAddresses, tf.storageSettings.AllowedIpAddresses)
@@ -375,7 +382,7 @@ func (tf *terraformWrapper) addStorageAccountNetworkRules() error {
 	for _, ipAddress := range addIpAddresses {
 		err = tf.addOrRemoveStorageAccountNetworkRule("add", ipAddress)
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 	}
 
@@ -383,15 +390,20 @@ func (tf *terraformWrapper) addStorageAccountNetworkRules() error {
 	for _, ipAddress := range removeIpAddresses {
 		err = tf.addOrRemoveStorageAccountNetworkRule("remove", ipAddress)
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 	}
 
+	if commands.IsDryRun() {
+		// the rules were not changed, so there is nothing to wait for
+		return nil
+	}
+
 	// ensure rules are applied to allow further processing
 	retryErrorText := "network rules not equal"
 	err = cmdutil.ExecuteFunctionWithRetry(
 		func() error {
-			currentAllowedIpAddresses, _ := tf.determineCurrentAllowedIpAddresses()
+			currentAllowedIpAddresses, _ := tf.determineCurrentAllowedIpAddresses(tf.executor.Try())
 			if reflect.DeepEqual(currentAllowedIpAddresses, tf.storageSettings.AllowedIpAddresses) {
 				return nil
 			} else {
@@ -399,7 +411,7 @@ func (tf *terraformWrapper) addStorageAccountNetworkRules() error {
 			}
 		}, tf.storageSettings.ContainerCreateRetryCount)
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	return nil
@@ -412,16 +424,18 @@ func (tf *terraformWrapper) addOrRemoveStorageAccountNetworkRule(method, value s
 		" --ip-address " + value
 
 	_, err := tf.executor.Execute(cmd)
-	return internal.ReturnErrorOrPanic(err)
+	return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 }
 
-func (tf *terraformWrapper) determineCurrentAllowedIpAddresses() ([]string, error) {
+// determineCurrentAllowedIpAddresses lists the allowed ip addresses of the state storage account with the given executor
+// (the executor of the recipe, or its Try view while waiting for the rules to be applied)
+func (tf *terraformWrapper) determineCurrentAllowedIpAddresses(executor commands.Executor) ([]string, error) {
 	networkRuleListCmd := "az storage account network-rule list" +
 		" --resource-group " + tf.resourceGroupName +
 		" --account-name " + tf.stateStorageAccountName +
 		" --query ipRules[].ipAddressOrRange -o json"
 
-	currentAllowedIpAddresses, err := tf.executor.Execute(networkRuleListCmd)
+	currentAllowedIpAddresses, err := executor.ReadOnly().Execute(networkRuleListCmd)
 
 	if err != nil {
 		return []string{}, err
@@ -439,13 +453,15 @@ func (tf *terraformWrapper) determineCurrentAllowedIpAddresses() ([]string, erro
 
 func (tf *terraformWrapper) guardAgainstUnsetVariables() error {
 	if !tf.variablesSet {
-		return errors.New("you should call SetVariables() before executing any of the terraform functions")
+		return ErrVariablesNotSet
 	}
 
 	return nil
 }
 
 func (tf *terraformWrapper) plan(isDestroy bool) (string, error) {
+	defer summary.StartStep("Terraform plan")()
+
 	if isDestroy {
 		logrus.Info("Creating the terraform destroy plan...")
 	} else {
@@ -454,32 +470,30 @@ func (tf *terraformWrapper) plan(isDestroy bool) (string, error) {
 
 	err := tf.guardAgainstUnsetVariables()
 	if err != nil {
-		return "", internal.ReturnErrorOrPanic(err)
+		return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
-	tfCommand := "terraform" +
-		" -chdir=" + tf.terraformDirectory +
-		" plan -input=false " +
+	tfArguments := "plan -input=false " +
 		" -var-file=" + tf.GetVariablesFileName() +
 		" -detailed-exitcode"
 
 	var localTerraformRelativePlanFilePath string
 
 	if isDestroy {
-		tfCommand += " -destroy"
+		tfArguments += " -destroy"
 		localTerraformRelativePlanFilePath, err = file_paths.GetLocalTerraformRelativePlanFilePath(tf.projectName, tf.terraformDirectory, true)
 		if err != nil {
-			return "", internal.ReturnErrorOrPanic(err)
+			return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 
-		tfCommand += " -out=" + localTerraformRelativePlanFilePath
+		tfArguments += " -out=" + localTerraformRelativePlanFilePath
 	} else {
 		localTerraformRelativePlanFilePath, err = file_paths.GetLocalTerraformRelativePlanFilePath(tf.projectName, tf.terraformDirectory, false)
 		if err != nil {
-			return "", internal.ReturnErrorOrPanic(err)
+			return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 
-		tfCommand += " -out=" + localTerraformRelativePlanFilePath
+		tfArguments += " -out=" + localTerraformRelativePlanFilePath
 	}
 
 	// before creating a new plan, make sure all files for this project are removed
@@ -487,18 +501,13 @@ func (tf *terraformWrapper) plan(isDestroy bool) (string, error) {
 		file_paths.GetPlanFileName(tf.projectName, isDestroy),
 		filepath.Join(tf.terraformDirectory, file_paths.PlansDirectory))
 	if err != nil {
-		return "", internal.ReturnErrorOrPanic(err)
+		return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
-	// disable global setting panic on error to allow terraform plan with
-	// detailed-exitcode for plan analyzing
-	panicOnError := error_handling.PanicOnAnyError
-	error_handling.PanicOnAnyError = false
-	defer func(panicOnError bool) {
-		error_handling.PanicOnAnyError = panicOnError
-	}(panicOnError)
-
-	plaintextPlanOutput, err := tf.executor.Execute(tfCommand)
+	// plan does not change the infrastructure, and is exactly what we want to see in dry-run mode. Executed as try,
+	// because with -detailed-exitcode, exit code 2 (changes present) is returned as error, which should neither panic
+	// nor show up as error of the CI run
+	plaintextPlanOutput, err := tf.runTerraform(tf.executor.ReadOnly().Try(), tfArguments)
 	// terraform plan with -detailed-exitcode results in the following exit codes
 	// 0 = Succeeded with empty diff (no changes)
 	// 1 = Error
@@ -509,22 +518,25 @@ func (tf *terraformWrapper) plan(isDestroy bool) (string, error) {
 		planIsDirty = false
 		break
 	case 1:
-		return "", internal.ReturnErrorOrPanic(err)
+		return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	case 2:
 		planIsDirty = true
 		break
 	default:
-		return "", internal.ReturnErrorOrPanic(fmt.Errorf("unexpected exit code %d in terraform plan command %w", exitCode, err))
+		return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(),
+			fmt.Errorf("unexpected exit code %d in terraform plan command %w", exitCode, err))
 	}
 
-	err = tf.persistPlanInAdditionalFormatsOnDisk(plaintextPlanOutput, localTerraformRelativePlanFilePath)
+	jsonPlanOutput, err := tf.persistPlanInAdditionalFormatsOnDisk(plaintextPlanOutput, localTerraformRelativePlanFilePath)
 	if err != nil {
-		return "", internal.ReturnErrorOrPanic(err)
+		return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
+	tf.recordPlannedChanges(jsonPlanOutput, isDestroy)
+
 	err = tf.persistAnalysisResultOnDisk(localTerraformRelativePlanFilePath, isDestroy, planIsDirty)
 	if err != nil {
-		return "", internal.ReturnErrorOrPanic(err)
+		return "", internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	return plaintextPlanOutput, nil
@@ -535,7 +547,7 @@ func (tf *terraformWrapper) applyFlow(isDestroy bool, planOnly bool, useExisting
 	var plan string
 
 	if planOnly && useExistingPlan {
-		return errors.New("planOnly with useExistingPlan makes no sense as a combination")
+		return ErrInvalidFlowOptions
 	}
 
 	if useExistingPlan {
@@ -546,7 +558,7 @@ func (tf *terraformWrapper) applyFlow(isDestroy bool, planOnly bool, useExisting
 		}
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 	} else {
 		if isDestroy {
@@ -556,7 +568,7 @@ func (tf *terraformWrapper) applyFlow(isDestroy bool, planOnly bool, useExisting
 		}
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 
 		// we show the plan to the user, but since the command output already logged it to the file, it is enough to pipe it
@@ -571,13 +583,16 @@ func (tf *terraformWrapper) applyFlow(isDestroy bool, planOnly bool, useExisting
 			}
 
 			if !approved {
-				approved = tf.executor.AskUserToConfirm("Do you want to apply the plan?")
+				// fails with a descriptive error in CI, instead of reading no answer as a "no"
+				approved, err = tf.executor.AskUserToConfirmE("Do you want to apply the plan?")
+				if err != nil {
+					return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
+				}
 			}
 
 			if !approved {
-				err := errors.New("plan was not approved")
-				logrus.Error(err)
-				return internal.ReturnErrorOrPanic(err)
+				logrus.Error(ErrPlanNotApproved)
+				return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), ErrPlanNotApproved)
 			}
 
 			if isDestroy {
@@ -587,7 +602,7 @@ func (tf *terraformWrapper) applyFlow(isDestroy bool, planOnly bool, useExisting
 			}
 
 			if err != nil {
-				return internal.ReturnErrorOrPanic(err)
+				return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 			}
 		}
 	}
@@ -596,6 +611,12 @@ func (tf *terraformWrapper) applyFlow(isDestroy bool, planOnly bool, useExisting
 }
 
 func (tf *terraformWrapper) forceApply(isDestroy bool) error {
+	if isDestroy {
+		defer summary.StartStep("Terraform destroy")()
+	} else {
+		defer summary.StartStep("Terraform apply")()
+	}
+
 	if isDestroy {
 		logrus.Info("Starting the terraform destroy...")
 	} else {
@@ -605,42 +626,56 @@ func (tf *terraformWrapper) forceApply(isDestroy bool) error {
 	err := tf.guardAgainstUnsetVariables()
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
-	tfCommand := "terraform" +
-		" -chdir=" + tf.terraformDirectory +
-		" apply" +
+	tfArguments := "apply" +
 		" -auto-approve -input=false"
 
 	if isDestroy {
-		tfCommand += " -destroy"
+		tfArguments += " -destroy"
 		path, err := file_paths.GetLocalTerraformRelativePlanFilePath(tf.projectName, tf.terraformDirectory, true)
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 
-		tfCommand += " \"" + path + "\""
+		tfArguments += " \"" + path + "\""
 	} else {
 		path, err := file_paths.GetLocalTerraformRelativePlanFilePath(tf.projectName, tf.terraformDirectory, false)
 
 		if err != nil {
-			return internal.ReturnErrorOrPanic(err)
+			return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 		}
 
-		tfCommand += " \"" + path + "\""
+		tfArguments += " \"" + path + "\""
 	}
 
-	_, err = tf.executor.Execute(tfCommand)
+	_, err = tf.runTerraform(tf.executor, tfArguments)
 
 	if err != nil {
-		return internal.ReturnErrorOrPanic(err)
+		return internal.ReturnErrorOrPanicWith(tf.executor.ErrorPolicy(), err)
 	}
 
 	return nil
 }
 
+// runTerraform executes terraform with the given arguments in the terraform directory. Subscription and tenant are passed
+// as environment variables of this single invocation (instead of the process environment), so that multiple terraform
+// projects for different subscriptions can be used within one process.
+func (tf *terraformWrapper) runTerraform(executor commands.Executor, arguments string, options ...commands.ExecuteOption) (string, error) {
+	options = append(options,
+		commands.WithDir(tf.terraformDirectory),
+		commands.WithEnv(map[string]string{
+			"ARM_SUBSCRIPTION_ID": tf.subscriptionId,
+			"ARM_TENANT_ID":       tf.tenantId,
+		}))
+
+	result, err := executor.Run(context.Background(), "terraform "+arguments, options...)
+
+	return strings.TrimSuffix(result.Stdout, "\n"), err
+}
+
 func trimLinebreakSuffixes(storageAccountKey string) string {
 	return strings.TrimRight(storageAccountKey, "\r\n")
 }
@@ -650,9 +685,9 @@ func getExitCode(err error) int {
 		return 0
 	}
 
-	var exitErr *exec.ExitError
+	var exitErr *commands.ExitError
 	if errors.As(err, &exitErr) {
-		return exitErr.ExitCode()
+		return exitErr.ExitCode
 	}
 
 	return 1
@@ -1,16 +1,18 @@
 package terraform
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/conplementag/cops-hq/v2/internal/summary"
 	"github.com/conplementag/cops-hq/v2/pkg/commands"
+	"github.com/conplementag/cops-hq/v2/pkg/commands/commandstest"
+	"github.com/conplementag/cops-hq/v2/pkg/error_handling"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -115,7 +117,7 @@ func Test_DeployFlow(t *testing.T) {
 				})).Once()
 
 				// the user confirmation is expected
-				executor.On("AskUserToConfirm", mock.Anything).Once()
+				executor.On("AskUserToConfirmE", mock.Anything).Once()
 
 				// the plan json will also be created
 				executor.On("Execute", mock.MatchedBy(func(command string) bool {
@@ -254,6 +256,113 @@ func Test_MultiplePlansCleanupCorrectly(t *testing.T) {
 	assertPlanFilesPresence(t, true, true, false)
 }
 
+func Test_DeployFlowWithFakeExecutor(t *testing.T) {
+	// Arrange
+	err := deleteDirectoryIfExists(".plans")
+	assert.NoError(t, err)
+
+	fake := commandstest.NewFakeExecutor()
+	fake.OnRegex(` plan -input=false .*-out=\.plans.test\.deploy\.tfplan`).
+		Returns("Terraform will perform the following actions").
+		WithExitCode(2)
+	fake.OnRegex(` show -json \.plans.test\.deploy\.tfplan`).Returns("{}")
+	fake.AnswerConfirmations(true)
+
+	tf := New(fake, projectName, "1234", "3214", "westeurope", "testrg", "storeaccount",
+		filepath.Join("."), DefaultBackendStorageSettings, DefaultDeploymentSettings)
+	tf.SetVariables(nil)
+
+	// Act
+	err = tf.DeployFlow(false, false, false)
+
+	// Assert
+	assert.NoError(t, err)
+	fake.AssertCommandOrder(t, " plan -input=false ", " show -json ", " apply -auto-approve ")
+	assertPlanFilesPresence(t, true, true, false)
+}
+
+func Test_DeployFlowRecordsPlannedChangesInRunSummary(t *testing.T) {
+	// Arrange
+	err := deleteDirectoryIfExists(".plans")
+	assert.NoError(t, err)
+
+	summary.Start("hq", "0.0.1", "")
+	t.Cleanup(summary.Stop)
+
+	fake := commandstest.NewFakeExecutor()
+	fake.OnRegex(` plan -input=false `).Returns("Terraform will perform the following actions").WithExitCode(2)
+	fake.OnRegex(` show -json `).Returns(`{"resource_changes": [` +
+		`{"change": {"actions": ["create"]}}, {"change": {"actions": ["update"]}}, {"change": {"actions": ["no-op"]}},` +
+		`{"change": {"actions": ["delete", "create"]}}, {"change": {"actions": ["delete"]}}]}`)
+
+	tf := New(fake, projectName, "1234", "3214", "westeurope", "testrg", "storeaccount",
+		filepath.Join("."), DefaultBackendStorageSettings, DefaultDeploymentSettings)
+	tf.SetVariables(nil)
+
+	// Act
+	err = tf.DeployFlow(false, false, true)
+
+	// Assert
+	assert.NoError(t, err)
+
+	runSummary := summary.Finish("", nil)
+	assert.Equal(t, []summary.TerraformChanges{{Project: projectName, Add: 2, Change: 1, Destroy: 2}},
+		runSummary.TerraformChanges)
+
+	var steps []string
+	for _, step := range runSummary.Steps {
+		steps = append(steps, step.Name)
+	}
+
+	assert.Equal(t, []string{"Terraform plan", "Terraform apply"}, steps)
+}
+
+func Test_DeclinedPlanReturnsErrPlanNotApproved(t *testing.T) {
+	// Arrange
+	err := deleteDirectoryIfExists(".plans")
+	assert.NoError(t, err)
+
+	fake := commandstest.NewFakeExecutor()
+	fake.OnRegex(` plan -input=false `).Returns("Terraform will perform the following actions").WithExitCode(2)
+	fake.OnRegex(` show -json `).Returns("{}")
+	fake.AnswerConfirmations(false)
+
+	tf := New(fake, projectName, "1234", "3214", "westeurope", "testrg", "storeaccount",
+		filepath.Join("."), DefaultBackendStorageSettings, DefaultDeploymentSettings)
+	tf.SetVariables(nil)
+
+	// Act
+	err = tf.DeployFlow(false, false, false)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrPlanNotApproved)
+	fake.AssertNotCalledMatching(t, " apply -auto-approve ")
+}
+
+func Test_DeployFlowFailsWithoutConfirmationWhenNonInteractive(t *testing.T) {
+	// Arrange
+	err := deleteDirectoryIfExists(".plans")
+	assert.NoError(t, err)
+
+	fake := commandstest.NewFakeExecutor()
+	fake.NonInteractive = true
+	fake.OnRegex(` plan -input=false .*-out=\.plans.test\.deploy\.tfplan`).
+		Returns("Terraform will perform the following actions").
+		WithExitCode(2)
+
+	tf := New(fake, projectName, "1234", "3214", "westeurope", "testrg", "storeaccount",
+		filepath.Join("."), DefaultBackendStorageSettings, DefaultDeploymentSettings)
+	tf.SetVariables(nil)
+
+	// Act
+	err = tf.DeployFlow(false, false, false)
+
+	// Assert
+	var nonInteractiveErr *commands.NonInteractiveError
+	assert.True(t, errors.As(err, &nonInteractiveErr))
+	fake.AssertNotCalledMatching(t, " apply -auto-approve ")
+}
+
 type executorMock struct {
 	mock.Mock
 	commands.Executor
@@ -268,14 +377,9 @@ func (e *executorMock) Execute(command string) (string, error) {
 
 	if strings.Contains(command, " plan ") {
 		if e.planHasChanges {
-			// Simulate terraform plan exit code 2 (changes present) - cross-platform compatible
-			var cmd *exec.Cmd
-			if runtime.GOOS == "windows" {
-				cmd = exec.Command("cmd", "/c", "exit 2")
-			} else {
-				cmd = exec.Command("sh", "-c", "exit 2")
-			}
-			return "Terraform will perform the following actions ... To perform exactly these actions, run the following command to apply", cmd.Run()
+			// Simulate terraform plan exit code 2 (changes present), the same way as the executor returns it
+			exitErr := &commands.ExitError{Command: command, ExitCode: 2, Cause: errors.New("exit status 2")}
+			return "Terraform will perform the following actions ... To perform exactly these actions, run the following command to apply", exitErr
 		} else {
 			return "Your infrastructure matches the configuration. ... found no differences, so no changes are needed", nil
 		}
@@ -284,6 +388,11 @@ func (e *executorMock) Execute(command string) (string, error) {
 	return "success - this output does not matter", nil
 }
 
+func (e *executorMock) Run(ctx context.Context, command string, options ...commands.ExecuteOption) (*commands.Result, error) {
+	output, err := e.Execute(command)
+	return &commands.Result{Command: command, Stdout: output}, err
+}
+
 func (e *executorMock) ExecuteSilent(command string) (string, error) {
 	if !e.isLooseMock {
 		e.Called(command)
@@ -292,12 +401,27 @@ func (e *executorMock) ExecuteSilent(command string) (string, error) {
 	return "success - this output does not matter", nil
 }
 
-func (e *executorMock) AskUserToConfirm(displayMessage string) bool {
+func (e *executorMock) ReadOnly() commands.Executor {
+	return e
+}
+
+func (e *executorMock) Try() commands.Executor {
+	return e
+}
+
+func (e *executorMock) ErrorPolicy() error_handling.Policy {
+	return error_handling.Default
+}
+
+func (e *executorMock) RegisterSecret(value string) {
+}
+
+func (e *executorMock) AskUserToConfirmE(displayMessage string) (bool, error) {
 	if !e.isLooseMock {
 		e.Called(displayMessage)
 	}
 
-	return true
+	return true, nil
 }
 
 type variablesStruct struct {